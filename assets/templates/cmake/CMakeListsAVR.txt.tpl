@@ -41,15 +41,29 @@ cmake_minimum_required(VERSION ${CMAKE_VERSION})
 project(${PROJECT_NAME} C CXX ASM)
 cmake_policy(SET CMP0023 OLD)
 
+# Build profile, applies to the target and all the dependencies
+set(CMAKE_BUILD_TYPE "{{BUILD_TYPE}}")
+add_compile_options({{PROFILE_FLAGS}})
+add_definitions({{PROFILE_DEFINITIONS}})
+
+# the optimisation level of the profile replaces the one of the toolchain and the build type
+if (NOT "{{PROFILE_OPTIMIZATION}}" STREQUAL "")
+    string(TOUPPER "${CMAKE_BUILD_TYPE}" BUILD_TYPE_FLAGS)
+    foreach (FLAGS CMAKE_C_FLAGS CMAKE_CXX_FLAGS CMAKE_C_FLAGS_${BUILD_TYPE_FLAGS} CMAKE_CXX_FLAGS_${BUILD_TYPE_FLAGS})
+        string(REGEX REPLACE "(^| )-O[0-9a-z]*" "" ${FLAGS} "${${FLAGS}}")
+    endforeach ()
+endif ()
+
 file(GLOB_RECURSE SRC_FILES
     "${PROJECT_PATH}/${ENTRY}/*.cpp"
     "${PROJECT_PATH}/${ENTRY}/*.cc"
     "${PROJECT_PATH}/${ENTRY}/*.c")
 
+# the port is a make variable so it is given when uploading: make upload WIO_UPLOAD_PORT=<port>
 generate_arduino_firmware(${TARGET_NAME}
     SRCS ${SRC_FILES}
     BOARD ${BOARD}
-    PORT {{PORT}})
+    PORT "{{UPLOAD_PORT}}")
 
 target_compile_definitions(
     ${TARGET_NAME}
@@ -0,0 +1,91 @@
+######################################################################
+# This is auto-generated by wio
+######################################################################
+
+set(CMAKE_VER 3.0.0)
+set(PROJECT_NAME {{PROJECT_NAME}})
+set(PROJECT_PATH "{{PROJECT_PATH}}")
+set(CMAKE_TOOLCHAIN_PATH "{{TOOLCHAIN_PATH}}")
+set(CMAKE_MODULE_PATH "${CMAKE_CURRENT_SOURCE_DIR}")
+set(DEPENDENCY_FILE dependencies)
+
+# C++ standard
+set(CMAKE_CXX_STANDARD {{CPP_STANDARD}})
+set(CMAKE_CXX_STANDARD_REQUIRED ON)
+set(CMAKE_CXX_EXTENSIONS OFF)
+
+# C standard
+set(CMAKE_C_STANDARD {{C_STANDARD}})
+set(CMAKE_C_STANDARD_REQUIRED ON)
+set(CMAKE_C_EXTENSIONS OFF)
+
+# all the paths toolchain can be at (this is because of different package managers)
+if (EXISTS "${CMAKE_TOOLCHAIN_PATH}/{{TOOLCHAIN_FILE_REL}}")
+    set(CMAKE_TOOLCHAIN_FILE "${CMAKE_TOOLCHAIN_PATH}/{{TOOLCHAIN_FILE_REL}}")
+elseif (EXISTS "${CMAKE_TOOLCHAIN_PATH}/../{{TOOLCHAIN_FILE_REL}}")
+    set(CMAKE_TOOLCHAIN_FILE "${CMAKE_TOOLCHAIN_PATH}/../{{TOOLCHAIN_FILE_REL}}")
+elseif (EXISTS "/usr/share/wio/{{TOOLCHAIN_FILE_REL}}")
+    set(CMAKE_TOOLCHAIN_FILE "/usr/share/wio/{{TOOLCHAIN_FILE_REL}}")
+else()
+    message(FATAL_ERROR "Failed to find AVR toolchain files!")
+endif()
+
+# properties
+set(TARGET_NAME {{TARGET_NAME}})
+set(PLATFORM {{PLATFORM}})
+set(FRAMEWORK {{FRAMEWORK}})
+set(BOARD {{BOARD}})
+set(ENTRY {{ENTRY}})
+set(MCU {{MCU}})
+set(F_CPU {{F_CPU}})
+
+cmake_minimum_required(VERSION ${CMAKE_VERSION})
+project(${PROJECT_NAME} C CXX ASM)
+cmake_policy(SET CMP0023 OLD)
+
+# Build profile, applies to the target and all the dependencies
+set(CMAKE_BUILD_TYPE "{{BUILD_TYPE}}")
+add_compile_options({{PROFILE_FLAGS}})
+add_definitions({{PROFILE_DEFINITIONS}})
+
+# the optimisation level of the profile replaces the one of the toolchain and the build type
+if (NOT "{{PROFILE_OPTIMIZATION}}" STREQUAL "")
+    string(TOUPPER "${CMAKE_BUILD_TYPE}" BUILD_TYPE_FLAGS)
+    foreach (FLAGS CMAKE_C_FLAGS CMAKE_CXX_FLAGS CMAKE_C_FLAGS_${BUILD_TYPE_FLAGS} CMAKE_CXX_FLAGS_${BUILD_TYPE_FLAGS})
+        string(REGEX REPLACE "(^| )-O[0-9a-z]*" "" ${FLAGS} "${${FLAGS}}")
+    endforeach ()
+endif ()
+
+# avr-libc alone, every source is compiled for the chip and clock of the target
+add_compile_options(-mmcu=${MCU} -ffunction-sections -fdata-sections)
+add_definitions(-DF_CPU=${F_CPU}UL)
+set(CMAKE_EXE_LINKER_FLAGS "${CMAKE_EXE_LINKER_FLAGS} -mmcu=${MCU} -Wl,--gc-sections")
+
+file(GLOB_RECURSE SRC_FILES
+    "${PROJECT_PATH}/${ENTRY}/*.cpp"
+    "${PROJECT_PATH}/${ENTRY}/*.cc"
+    "${PROJECT_PATH}/${ENTRY}/*.c"
+    "${PROJECT_PATH}/${ENTRY}/*.S")
+
+add_executable(${TARGET_NAME} ${SRC_FILES})
+set_target_properties(${TARGET_NAME} PROPERTIES SUFFIX ".elf")
+
+# the image uploaded to the chip
+add_custom_command(TARGET ${TARGET_NAME} POST_BUILD
+    COMMAND ${AVR_OBJCOPY} -O ihex -R .eeprom
+        "$<TARGET_FILE:${TARGET_NAME}>" "${CMAKE_CURRENT_BINARY_DIR}/${TARGET_NAME}.hex"
+    COMMENT "Generating ${TARGET_NAME}.hex")
+
+target_compile_definitions(
+    ${TARGET_NAME}
+    PRIVATE
+    WIO_PLATFORM_${PLATFORM}
+    WIO_FRAMEWORK_${FRAMEWORK}
+    WIO_BOARD_${BOARD}
+    {{TARGET_COMPILE_DEFINITIONS}})
+
+target_compile_options(${TARGET_NAME}
+    PRIVATE
+    {{TARGET_COMPILE_FLAGS}})
+
+include(${DEPENDENCY_FILE})
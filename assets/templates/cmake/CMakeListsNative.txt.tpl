@@ -34,6 +34,23 @@ set(PLATFORM {{PLATFORM}})
 set(FRAMEWORK {{FRAMEWORK}})
 set(BOARD {{BOARD}})
 
+# Build profile, applies to the target and all the dependencies
+set(CMAKE_BUILD_TYPE "{{BUILD_TYPE}}")
+add_compile_options({{PROFILE_FLAGS}})
+add_definitions({{PROFILE_DEFINITIONS}})
+
+# the optimisation level of the profile replaces the one of the toolchain and the build type
+if (NOT "{{PROFILE_OPTIMIZATION}}" STREQUAL "")
+    string(TOUPPER "${CMAKE_BUILD_TYPE}" BUILD_TYPE_FLAGS)
+    foreach (FLAGS CMAKE_C_FLAGS CMAKE_CXX_FLAGS CMAKE_C_FLAGS_${BUILD_TYPE_FLAGS} CMAKE_CXX_FLAGS_${BUILD_TYPE_FLAGS})
+        string(REGEX REPLACE "(^| )-O[0-9a-z]*" "" ${FLAGS} "${${FLAGS}}")
+    endforeach ()
+endif ()
+
+# Build variant, applies to the target and all the dependencies
+add_compile_options({{VARIANT_COMPILE_FLAGS}})
+set(CMAKE_EXE_LINKER_FLAGS "${CMAKE_EXE_LINKER_FLAGS} {{VARIANT_LINK_FLAGS}}")
+
 file(GLOB_RECURSE ${TARGET_NAME}_files
     ${PROJECT_PATH}/${ENTRY}/*.cpp
     ${PROJECT_PATH}/${ENTRY}/*.cc
@@ -36,7 +36,7 @@ var createFlags = []cli.Flag{
     },
     cli.StringFlag{
         Name:  "framework",
-        Usage: "Target framework: 'Arduino', 'Cosa', or 'all'",
+        Usage: "Target framework: 'Arduino', 'Cosa', 'Baremetal', or 'all'",
         Value: "all",
     },
     cli.StringFlag{
@@ -78,9 +78,43 @@ var buildFlags = []cli.Flag{
         Name:  "all",
         Usage: "Build all available targets",
     },
+    cli.IntFlag{
+        Name:  "jobs, j",
+        Usage: "Number of parallel jobs shared by all the targets (default: number of CPUs + 2)",
+    },
+    cli.BoolFlag{
+        Name:  "fail-fast",
+        Usage: "Stop building remaining targets after the first failure",
+    },
     cli.StringFlag{
-        Name:  "port",
-        Usage: "Specify upload port",
+        Name:  "profile",
+        Usage: "Build profile: 'debug', 'release', 'minsize', or one defined in wio.yml",
+    },
+    cli.StringFlag{
+        Name:  "sanitize",
+        Usage: "Build native targets with sanitizers in their own directory, e.g. 'address,undefined'",
+    },
+    cli.BoolFlag{
+        Name:  "group-output",
+        Usage: "Print the build output of each target as one block once it is done",
+    },
+    cli.BoolFlag{
+        Name:  "build-log",
+        Usage: "Write the full build output of each target to .wio/targets/<target>/build.log",
+    },
+    cli.StringFlag{
+        Name:  "diagnostics-format",
+        Usage: "Format of the compiler diagnostics summary: 'text', 'json', or 'none'",
+        Value: "text",
+    },
+    cli.StringFlag{
+        Name:  "diagnostics-output",
+        Usage: "File to write JSON diagnostics to, needed with --diagnostics-format json",
+    },
+    cli.StringFlag{
+        Name:   "port",
+        Usage:  "Deprecated, the port is only used when uploading",
+        Hidden: true,
     },
     cli.BoolFlag{
         Name:  "verbose",
@@ -97,17 +131,268 @@ var cleanFlags = []cli.Flag{
         Name:  "hard",
         Usage: "Removes build directories",
     },
+    cli.StringFlag{
+        Name:  "profile",
+        Usage: "Clean the build directories of this profile: 'debug', 'release', 'minsize', or one defined in wio.yml",
+    },
+    cli.StringFlag{
+        Name:  "sanitize",
+        Usage: "Clean the build directories of native targets built with these sanitizers, e.g. 'address,undefined'",
+    },
+}
+
+var testFlags = []cli.Flag{
+    cli.IntFlag{
+        Name:  "jobs, j",
+        Usage: "Number of parallel jobs shared by all the targets (default: number of CPUs + 2)",
+    },
+    cli.StringFlag{
+        Name:  "args",
+        Usage: "Arguments passed to test executables",
+    },
+    cli.DurationFlag{
+        Name:  "timeout",
+        Usage: "Time after which a running test is stopped and marked as failed, e.g. '30s'",
+        Value: defaults.TestTimeout,
+    },
+    cli.StringFlag{
+        Name:  "junit",
+        Usage: "Write a JUnit XML report of the results to this file",
+    },
+    cli.BoolFlag{
+        Name:  "simulate",
+        Usage: "Run AVR test targets in the simavr simulator",
+    },
+    cli.StringFlag{
+        Name:  "port",
+        Usage: "Upload AVR test targets to the device on this port and read their results over serial",
+    },
+    cli.IntFlag{
+        Name:  "baud",
+        Usage: "Baud rate used to read test results from the device, if the target does not set one",
+        Value: defaults.Baud,
+    },
+    cli.StringFlag{
+        Name:  "sentinel",
+        Usage: "Regular expression matching the line test firmware prints when it is done (default: OK, FAIL or DONE)",
+    },
+    cli.StringFlag{
+        Name:  "profile",
+        Usage: "Build profile: 'debug', 'release', 'minsize', or one defined in wio.yml",
+    },
+    cli.StringFlag{
+        Name:  "sanitize",
+        Usage: "Build native targets with sanitizers in their own directory, e.g. 'address,undefined'",
+    },
+    cli.BoolFlag{
+        Name:  "coverage",
+        Usage: "Build native test targets with coverage instrumentation and report the covered lines",
+    },
+    cli.BoolFlag{
+        Name:  "coverage-deps",
+        Usage: "Include the sources of node_modules dependencies in the coverage report",
+    },
+    cli.StringFlag{
+        Name:  "coverage-output",
+        Usage: "Directory where lcov.info and cobertura.xml are written (default: .wio/coverage)",
+    },
+    cli.StringFlag{
+        Name:  "gcov",
+        Usage: "Command used to process coverage data, e.g. 'gcov-8' or 'llvm-cov gcov' (default: based on the compiler)",
+    },
+    cli.BoolFlag{
+        Name:  "group-output",
+        Usage: "Print the output of each target as one block once it is done",
+    },
+    cli.BoolFlag{
+        Name:  "build-log",
+        Usage: "Write the full build output of each target to .wio/targets/<target>/build.log",
+    },
+    cli.BoolFlag{
+        Name:  "verbose",
+        Usage: "Turns verbose mode on to show detailed errors and commands being executed.",
+    },
+    cli.BoolFlag{
+        Name:  "disable-warnings",
+        Usage: "Disables all the warning shown by wio",
+    },
+}
+
+var compdbFlags = []cli.Flag{
+    cli.BoolFlag{
+        Name:  "all",
+        Usage: "Merge the compilation databases of all available targets",
+    },
+    cli.IntFlag{
+        Name:  "jobs, j",
+        Usage: "Number of parallel jobs shared by all the targets (default: number of CPUs + 2)",
+    },
+    cli.BoolFlag{
+        Name:  "verbose",
+        Usage: "Turns verbose mode on to show detailed errors and commands being executed.",
+    },
+    cli.BoolFlag{
+        Name:  "disable-warnings",
+        Usage: "Disables all the warning shown by wio",
+    },
+}
+
+var sizeFlags = []cli.Flag{
+    cli.BoolFlag{
+        Name:  "by-package",
+        Usage: "Attribute the memory used by the firmware to the packages it is built from",
+    },
+    cli.StringFlag{
+        Name:  "json",
+        Usage: "Write the size by package as JSON to this file",
+    },
+    cli.StringFlag{
+        Name:  "diff",
+        Usage: "Compare the size by package with a JSON report of a previous build",
+    },
+    cli.StringFlag{
+        Name:  "profile",
+        Usage: "Build profile the targets were built with",
+    },
+    cli.BoolFlag{
+        Name:  "verbose",
+        Usage: "Turns verbose mode on to show detailed errors and commands being executed.",
+    },
+    cli.BoolFlag{
+        Name:  "disable-warnings",
+        Usage: "Disables all the warning shown by wio",
+    },
+}
+
+var uploadFlags = []cli.Flag{
+    cli.StringFlag{
+        Name:  "port",
+        Usage: "Port of the device, or of the programmer if not connected over usb. Separate ports with commas to upload to each",
+    },
+    cli.StringFlag{
+        Name:  "programmer",
+        Usage: "Upload with a programmer instead of the bootloader: e.g. 'usbasp', 'avrispmkii', 'arduino-as-isp'",
+    },
+    cli.StringFlag{
+        Name:  "avrdude-flags",
+        Usage: "Extra flags passed to avrdude, e.g. '-B 32'",
+    },
+    cli.IntFlag{
+        Name:  "upload-baud",
+        Usage: "Baud rate of the bootloader or programmer, for boards that do not use the usual one",
+    },
+    cli.StringFlag{
+        Name:  "reset",
+        Usage: "How the board enters its bootloader: 'auto', '1200bps' or 'none' if it is already in it",
+    },
+    cli.BoolFlag{
+        Name:  "fuses",
+        Usage: "Also write the fuses of the board or the ones set in the upload section of the target",
+    },
+    cli.BoolFlag{
+        Name:  "dry-run",
+        Usage: "Print the avrdude commands instead of running them",
+    },
+    cli.StringFlag{
+        Name:  "profile",
+        Usage: "Build profile: 'debug', 'release', 'minsize', or one defined in wio.yml",
+    },
+    cli.IntFlag{
+        Name:  "jobs, j",
+        Usage: "Number of parallel jobs shared by all the targets (default: number of CPUs + 2)",
+    },
+    cli.BoolFlag{
+        Name:  "verbose",
+        Usage: "Turns verbose mode on to show detailed errors and commands being executed.",
+    },
+    cli.BoolFlag{
+        Name:  "disable-warnings",
+        Usage: "Disables all the warning shown by wio",
+    },
+}
+
+var bootloaderFlags = []cli.Flag{
+    cli.StringFlag{
+        Name:  "programmer",
+        Usage: "Programmer used to write the bootloader: e.g. 'usbasp', 'avrispmkii', 'arduino-as-isp'",
+    },
+    cli.StringFlag{
+        Name:  "port",
+        Usage: "Port of the programmer if it is not connected over usb",
+    },
+    cli.StringFlag{
+        Name:  "bootloader",
+        Usage: "Bootloader image to write instead of the one of the board",
+    },
+    cli.StringFlag{
+        Name:  "avrdude-flags",
+        Usage: "Extra flags passed to avrdude, e.g. '-B 32'",
+    },
+    cli.BoolFlag{
+        Name:  "dry-run",
+        Usage: "Print the avrdude commands instead of running them",
+    },
+    cli.BoolFlag{
+        Name:  "verbose",
+        Usage: "Turns verbose mode on to show detailed errors and commands being executed.",
+    },
+    cli.BoolFlag{
+        Name:  "disable-warnings",
+        Usage: "Disables all the warning shown by wio",
+    },
 }
 
 var runFlags = []cli.Flag{
     cli.StringFlag{
         Name:  "port",
-        Usage: "Specify upload port",
+        Usage: "Upload port, or several separated by commas to upload to each of them",
+    },
+    cli.StringFlag{
+        Name:  "programmer",
+        Usage: "Upload AVR targets with a programmer instead of the bootloader",
+    },
+    cli.IntFlag{
+        Name:  "upload-baud",
+        Usage: "Baud rate of the bootloader or programmer, for boards that do not use the usual one",
+    },
+    cli.StringFlag{
+        Name:  "reset",
+        Usage: "How the board enters its bootloader: 'auto', '1200bps' or 'none' if it is already in it",
+    },
+    cli.BoolFlag{
+        Name:  "monitor",
+        Usage: "Open the serial monitor once the AVR target is uploaded, it reconnects after later uploads",
+    },
+    cli.IntFlag{
+        Name:  "baud",
+        Usage: "Baud rate of the serial monitor opened with --monitor, if the target does not set one",
+        Value: defaults.Baud,
     },
     cli.StringFlag{
         Name:  "args",
         Usage: "Arguments passed to executable",
     },
+    cli.StringFlag{
+        Name:  "profile",
+        Usage: "Build profile: 'debug', 'release', 'minsize', or one defined in wio.yml",
+    },
+    cli.StringFlag{
+        Name:  "sanitize",
+        Usage: "Build native targets with sanitizers in their own directory, e.g. 'address,undefined'",
+    },
+    cli.BoolFlag{
+        Name:  "simulate",
+        Usage: "Run AVR targets in the simavr simulator instead of uploading them",
+    },
+    cli.DurationFlag{
+        Name:  "timeout",
+        Usage: "Time after which a simulated target is stopped, e.g. '30s', or 0 to let it run until it exits",
+        Value: defaults.SimulateTimeout,
+    },
+    cli.IntFlag{
+        Name:  "jobs, j",
+        Usage: "Number of parallel jobs shared by all the targets (default: number of CPUs + 2)",
+    },
     cli.BoolFlag{
         Name:  "verbose",
         Usage: "Turns verbose mode on to show detailed errors and commands being executed.",
@@ -180,6 +465,51 @@ var commands = []cli.Command{
             command = run.Run{Context: c, RunType: run.TypeRun}
         },
     },
+    {
+        Name:      "test",
+        Usage:     "Builds and runs test targets and reports the results.",
+        UsageText: "wio test [targets] [command options]",
+        Flags:     testFlags,
+        Action: func(c *cli.Context) {
+            command = run.Run{Context: c, RunType: run.TypeTest}
+        },
+    },
+    {
+        Name:      "compdb",
+        Usage:     "Generates compile_commands.json in the project root for editors and tools.",
+        UsageText: "wio compdb [targets] [command options]",
+        Flags:     compdbFlags,
+        Action: func(c *cli.Context) {
+            command = run.Run{Context: c, RunType: run.TypeCompdb}
+        },
+    },
+    {
+        Name:      "upload",
+        Usage:     "Uploads AVR targets through their bootloader or with a programmer.",
+        UsageText: "wio upload [targets] [command options]",
+        Flags:     uploadFlags,
+        Action: func(c *cli.Context) {
+            command = run.Run{Context: c, RunType: run.TypeUpload}
+        },
+    },
+    {
+        Name:      "burn-bootloader",
+        Usage:     "Sets the fuses and writes the bootloader of the board of AVR targets with a programmer.",
+        UsageText: "wio burn-bootloader [targets] [command options]",
+        Flags:     bootloaderFlags,
+        Action: func(c *cli.Context) {
+            command = run.Run{Context: c, RunType: run.TypeBurnBootloader}
+        },
+    },
+    {
+        Name:      "size",
+        Usage:     "Shows the flash and RAM used by built targets.",
+        UsageText: "wio size [targets] [command options]",
+        Flags:     sizeFlags,
+        Action: func(c *cli.Context) {
+            command = run.Run{Context: c, RunType: run.TypeSize}
+        },
+    },
     {
         Name:  "vendor",
         Usage: "Manage locally vendored dependencies.",
@@ -262,10 +592,47 @@ var commands = []cli.Command{
                         Usage: "Baud rate for the Serial port.",
                         Value: defaults.Baud},
                     cli.StringFlag{Name: "port",
-                        Usage: "Serial Port to open.",
+                        Usage: "Serial Port to open, or one shared over the network: 'tcp://host:port' or 'rfc2217://host:port'.",
                         Value: defaults.Port},
+                    cli.StringFlag{Name: "target",
+                        Usage: "Target of the project in this directory whose monitor settings are used (Default: the default target)."},
+                    cli.IntFlag{Name: "data-bits",
+                        Usage: "Number of data bits: 5, 6, 7 or 8.",
+                        Value: 8},
+                    cli.StringFlag{Name: "parity",
+                        Usage: "Parity: 'none', 'odd', 'even', 'mark' or 'space'.",
+                        Value: "none"},
+                    cli.StringFlag{Name: "stop-bits",
+                        Usage: "Number of stop bits: 1, 1.5 or 2.",
+                        Value: "1"},
+                    cli.StringFlag{Name: "line-ending",
+                        Usage: "Line ending added to what is sent: 'none', 'lf', 'cr' or 'crlf'.",
+                        Value: "lf"},
+                    cli.BoolFlag{Name: "raw",
+                        Usage: "Sends every key as it is typed instead of line by line."},
+                    cli.BoolFlag{Name: "echo",
+                        Usage: "Prints what is sent to the device."},
                     cli.BoolFlag{Name: "gui",
-                        Usage: "Runs the GUI version of the serial monitor tool"},
+                        Usage: "Opens the serial monitor in the browser"},
+                    cli.BoolFlag{Name: "reconnect",
+                        Usage: "Waits for the device to come back when it disconnects, e.g. during an upload."},
+                    cli.BoolFlag{Name: "timestamp",
+                        Usage: "Prefixes every line with the time it was received."},
+                    cli.BoolFlag{Name: "hex",
+                        Usage: "Shows the data as a hex dump, for binary protocols."},
+                    cli.StringFlag{Name: "filter",
+                        Usage: "Regular expression matched against every line."},
+                    cli.StringFlag{Name: "filter-mode",
+                        Usage: "What to do with lines matching the filter: 'highlight', 'include' or 'exclude'.",
+                        Value: "highlight"},
+                    cli.StringFlag{Name: "log-file",
+                        Usage: "Also writes everything received, unfiltered, to this file."},
+                    cli.StringFlag{Name: "log-max-size",
+                        Usage: "Size at which the log file is rotated, e.g. '512K' or '10M'.",
+                        Value: "10M"},
+                    cli.IntFlag{Name: "log-backups",
+                        Usage: "Number of rotated log files kept.",
+                        Value: 5},
                     cli.BoolFlag{Name: "disable-warnings",
                         Usage: "Disables all the warning shown by wio.",
                     },
@@ -283,6 +650,9 @@ var commands = []cli.Command{
                         Usage: "Shows only the name of the ports."},
                     cli.BoolFlag{Name: "show-all",
                         Usage: "Shows all the ports, closed or open (Default: only open devices)."},
+                    cli.StringFlag{Name: "format",
+                        Usage: "Output format: 'text', 'json' or 'csv'",
+                        Value: "text"},
                     cli.BoolFlag{Name: "verbose",
                         Usage: "Turns verbose mode on to show detailed errors and commands being executed."},
                     cli.BoolFlag{Name: "disable-warnings",
@@ -293,6 +663,90 @@ var commands = []cli.Command{
                     command = devices.Devices{Context: c, Type: devices.LIST}
                 },
             },
+            cli.Command{
+                Name:      "watch",
+                Usage:     "Prints devices/ports as they are connected and disconnected.",
+                UsageText: "wio devices watch [command options]",
+                Flags: []cli.Flag{
+                    cli.StringFlag{Name: "format",
+                        Usage: "Output format: 'text', 'json' (one event per line) or 'csv'",
+                        Value: "text"},
+                    cli.StringFlag{Name: "until",
+                        Usage: "Stop at the first 'add' or 'remove' event"},
+                    cli.StringFlag{Name: "port",
+                        Usage: "Only report events of this port"},
+                    cli.DurationFlag{Name: "interval",
+                        Usage: "How often the ports are listed",
+                        Value: 500 * time.Millisecond},
+                    cli.DurationFlag{Name: "timeout",
+                        Usage: "Stop watching after this long, an error with --until (Default: no timeout)"},
+                    cli.BoolFlag{Name: "verbose",
+                        Usage: "Turns verbose mode on to show detailed errors and commands being executed."},
+                    cli.BoolFlag{Name: "disable-warnings",
+                        Usage: "Disables all the warning shown by wio.",
+                    },
+                },
+                Action: func(c *cli.Context) {
+                    command = devices.Devices{Context: c, Type: devices.WATCH}
+                },
+            },
+            cli.Command{
+                Name:      "plot",
+                Usage:     "Plots the numbers a device sends as csv or key:value lines.",
+                UsageText: "wio devices plot [command options]",
+                Flags: []cli.Flag{
+                    cli.IntFlag{Name: "baud",
+                        Usage: "Baud rate for the Serial port.",
+                        Value: defaults.Baud},
+                    cli.StringFlag{Name: "port",
+                        Usage: "Serial Port to open.",
+                        Value: defaults.Port},
+                    cli.StringFlag{Name: "series",
+                        Usage: "Names or csv column numbers of the series to plot, separated by commas (Default: all)"},
+                    cli.IntFlag{Name: "width",
+                        Usage: "Number of values shown for each series",
+                        Value: 60},
+                    cli.BoolFlag{Name: "ascii",
+                        Usage: "Draws the charts with ascii characters only"},
+                    cli.StringFlag{Name: "output",
+                        Usage: "Exports every value to this csv file as time, series and value"},
+                    cli.BoolFlag{Name: "verbose",
+                        Usage: "Turns verbose mode on to show detailed errors and commands being executed."},
+                    cli.BoolFlag{Name: "disable-warnings",
+                        Usage: "Disables all the warning shown by wio.",
+                    },
+                },
+                Action: func(c *cli.Context) {
+                    command = devices.Devices{Context: c, Type: devices.PLOT}
+                },
+            },
+            cli.Command{
+                Name:      "serve",
+                Usage:     "Shares a serial port over the network, for monitors on other machines.",
+                UsageText: "wio devices serve [command options]",
+                Flags: []cli.Flag{
+                    cli.StringFlag{Name: "port",
+                        Usage: "Serial Port to share.",
+                        Value: defaults.Port},
+                    cli.IntFlag{Name: "baud",
+                        Usage: "Baud rate the port is opened with, rfc2217 clients can change it.",
+                        Value: defaults.Baud},
+                    cli.StringFlag{Name: "listen",
+                        Usage: "Address to listen on, e.g. ':4000' to accept clients from other machines",
+                        Value: "localhost:4000"},
+                    cli.StringFlag{Name: "protocol",
+                        Usage: "Protocol spoken with clients: 'rfc2217' or 'raw' for plain tcp",
+                        Value: "rfc2217"},
+                    cli.BoolFlag{Name: "verbose",
+                        Usage: "Turns verbose mode on to show detailed errors and commands being executed."},
+                    cli.BoolFlag{Name: "disable-warnings",
+                        Usage: "Disables all the warning shown by wio.",
+                    },
+                },
+                Action: func(c *cli.Context) {
+                    command = devices.Devices{Context: c, Type: devices.SERVE}
+                },
+            },
         },
     },
 }
@@ -352,6 +806,9 @@ func main() {
     err := wio()
     if err != nil {
         log.Errln(err.Error())
+        if exitError, ok := err.(cmd.ExitError); ok {
+            os.Exit(exitError.ExitCode())
+        }
         os.Exit(1)
     }
 }
@@ -14,6 +14,11 @@ type ExitError struct {
     error
 }
 
+// Creates an error that makes wio exit with the given code
+func NewExitError(code int, err error) ExitError {
+    return ExitError{code: code, error: err}
+}
+
 func (exitError ExitError) ExitCode() int {
     return exitError.code
 }
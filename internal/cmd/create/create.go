@@ -128,6 +128,7 @@ func fillAppConfig(info *createInfo) error {
                 Platform:  getPlatform(info.platform),
                 Framework: getFramework(info.framework),
                 Board:     getBoard(info.board),
+                Test:      defaults.App.Test,
             },
         },
     }
@@ -153,6 +154,7 @@ func fillPackageConfig(info *createInfo) error {
                 Platform:  getPlatform(info.platform),
                 Framework: getFramework(info.framework),
                 Board:     getBoard(info.board),
+                Test:      defaults.Pkg.Test,
             },
         },
     }
@@ -0,0 +1,221 @@
+package devices
+
+import (
+    "bufio"
+    "bytes"
+    "errors"
+    "fmt"
+    "io"
+    "strings"
+    "time"
+    "wio/pkg/util"
+)
+
+// Line endings appended to what is sent to the device
+var lineEndings = map[string]string{
+    "none": "",
+    "lf":   "\n",
+    "cr":   "\r",
+    "crlf": "\r\n",
+}
+
+func GetLineEnding(name string) (string, error) {
+    ending, exists := lineEndings[strings.ToLower(name)]
+    if !exists {
+        return "", util.Error("line ending [%s] is not supported, use none, lf, cr or crlf", name)
+    }
+    return ending, nil
+}
+
+const (
+    // Ctrl+T starts a hotkey, the key after it picks the command
+    menuKey = 0x14
+    // Ctrl+] and Ctrl+C quit in raw mode, where the terminal does not handle them
+    quitKey      = 0x1d
+    interruptKey = 0x03
+)
+
+// How long the board is held in reset
+var resetPulse = 100 * time.Millisecond
+
+var errQuit = errors.New("quit")
+
+const hotkeyHelp = `--- Hotkeys: Ctrl+T followed by
+---   d  toggle DTR        r  toggle RTS
+---   b  reset the board   e  toggle local echo
+---   q  quit              h  show this help
+---   Ctrl+T sends Ctrl+T to the device
+`
+
+// Serial port as far as the console uses it
+type consoleDevice interface {
+    io.Writer
+    SetDTR(dtr bool) error
+    SetRTS(rts bool) error
+}
+
+// Forwards what is typed to the device and runs the hotkeys
+type console struct {
+    device consoleDevice
+    // echo of what is sent and messages of the hotkeys
+    out    io.Writer
+    ending string
+    echo   bool
+    dtr    bool
+    rts    bool
+}
+
+// Ports are opened with both DTR and RTS set
+func newConsole(device consoleDevice, out io.Writer, ending string, echo bool) *console {
+    return &console{device: device, out: out, ending: ending, echo: echo, dtr: true, rts: true}
+}
+
+func (c *console) message(format string, args ...interface{}) {
+    fmt.Fprintf(c.out, "\n--- "+format+" ---\n", args...)
+}
+
+func onOff(value bool) string {
+    if value {
+        return "on"
+    }
+    return "off"
+}
+
+func (c *console) send(data []byte) error {
+    if c.echo {
+        c.out.Write(data)
+    }
+    _, err := c.device.Write(data)
+    return c.deviceError(err)
+}
+
+// While a reconnecting monitor waits for the device, what is typed is dropped with a message
+// instead of ending the console
+func (c *console) deviceError(err error) error {
+    if err == errDisconnected {
+        c.message("not sent, the device is disconnected")
+        return nil
+    }
+    return err
+}
+
+// Pulls DTR and RTS low for a moment, which resets Arduino boards through their auto reset circuit
+func (c *console) reset() error {
+    if err := c.device.SetDTR(false); err != nil {
+        return err
+    }
+    if err := c.device.SetRTS(false); err != nil {
+        return err
+    }
+    time.Sleep(resetPulse)
+    if err := c.device.SetDTR(true); err != nil {
+        return err
+    }
+    c.dtr, c.rts = true, true
+    return c.device.SetRTS(true)
+}
+
+// Runs the command of the key pressed after Ctrl+T
+func (c *console) command(key byte) error {
+    switch key {
+    case 'd', 'D', 0x04:
+        c.dtr = !c.dtr
+        if err := c.device.SetDTR(c.dtr); err != nil {
+            return c.deviceError(err)
+        }
+        c.message("DTR %s", onOff(c.dtr))
+    case 'r', 'R', 0x12:
+        c.rts = !c.rts
+        if err := c.device.SetRTS(c.rts); err != nil {
+            return c.deviceError(err)
+        }
+        c.message("RTS %s", onOff(c.rts))
+    case 'b', 'B', 0x02:
+        if err := c.reset(); err != nil {
+            return c.deviceError(err)
+        }
+        c.message("board reset")
+    case 'e', 'E', 0x05:
+        c.echo = !c.echo
+        c.message("local echo %s", onOff(c.echo))
+    case 'q', 'Q', 0x11:
+        return errQuit
+    case menuKey:
+        return c.send([]byte{menuKey})
+    case 'h', 'H', '?', 0x08:
+        fmt.Fprint(c.out, "\n"+hotkeyHelp)
+    default:
+        c.message("unknown hotkey, Ctrl+T h shows the hotkeys")
+    }
+    return nil
+}
+
+// Sends each line read with the line ending once it is complete. Lines made of Ctrl+T and a key
+// run that hotkey. Returns errQuit if the user quit and nil once the input is closed
+func (c *console) forwardLines(in io.Reader) error {
+    reader := bufio.NewReader(in)
+    for {
+        line, err := reader.ReadString('\n')
+        line = strings.TrimRight(line, "\r\n")
+        if len(line) >= 2 && line[0] == menuKey {
+            if err := c.command(line[1]); err != nil {
+                return err
+            }
+        } else if line != "" || err == nil {
+            if err := c.send([]byte(line + c.ending)); err != nil {
+                return err
+            }
+        }
+        if err == io.EOF {
+            return nil
+        } else if err != nil {
+            return err
+        }
+    }
+}
+
+// Sends every key as it is typed, with Enter replaced by the line ending unless there is none.
+// Returns errQuit if the user quit and nil once the input is closed
+func (c *console) forwardRaw(in io.Reader) error {
+    buffer := make([]byte, 64)
+    menu := false
+    for {
+        n, err := in.Read(buffer)
+        for _, key := range buffer[:n] {
+            var sendErr error
+            switch {
+            case menu:
+                menu = false
+                sendErr = c.command(key)
+            case key == menuKey:
+                menu = true
+            case key == quitKey || key == interruptKey:
+                return errQuit
+            case (key == '\r' || key == '\n') && c.ending != "":
+                sendErr = c.send([]byte(c.ending))
+            default:
+                sendErr = c.send([]byte{key})
+            }
+            if sendErr != nil {
+                return sendErr
+            }
+        }
+        if err == io.EOF {
+            return nil
+        } else if err != nil {
+            return err
+        }
+    }
+}
+
+// Terminals in raw mode do not return the carriage on a new line
+type crlfWriter struct {
+    out io.Writer
+}
+
+func (w crlfWriter) Write(p []byte) (int, error) {
+    if _, err := w.out.Write(bytes.Replace(p, []byte("\n"), []byte("\r\n"), -1)); err != nil {
+        return 0, err
+    }
+    return len(p), nil
+}
@@ -0,0 +1,123 @@
+package devices
+
+import (
+    "bytes"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "go.bug.st/serial.v1"
+)
+
+type fakeDevice struct {
+    bytes.Buffer
+    lines []string
+}
+
+func (d *fakeDevice) SetDTR(dtr bool) error {
+    d.lines = append(d.lines, "dtr "+onOff(dtr))
+    return nil
+}
+
+func (d *fakeDevice) SetRTS(rts bool) error {
+    d.lines = append(d.lines, "rts "+onOff(rts))
+    return nil
+}
+
+func TestForwardLines(t *testing.T) {
+    device := &fakeDevice{}
+    out := &bytes.Buffer{}
+    c := newConsole(device, out, "\r\n", false)
+
+    err := c.forwardLines(strings.NewReader("led on\n\nstatus\r\n\x14d\npartial"))
+    assert.Nil(t, err)
+    assert.Equal(t, "led on\r\n\r\nstatus\r\npartial\r\n", device.String())
+    assert.Equal(t, []string{"dtr off"}, device.lines)
+    assert.Contains(t, out.String(), "--- DTR off ---")
+
+    err = c.forwardLines(strings.NewReader("before\n\x14q\nafter\n"))
+    assert.Equal(t, errQuit, err)
+    assert.NotContains(t, device.String(), "after")
+}
+
+func TestForwardRaw(t *testing.T) {
+    resetPulse = 0
+    device := &fakeDevice{}
+    out := &bytes.Buffer{}
+    c := newConsole(device, out, "\n", false)
+
+    err := c.forwardRaw(strings.NewReader("ab\r\x14e\x14\x14c\x14b\x14rz"))
+    assert.Nil(t, err)
+    assert.Equal(t, "ab\n\x14cz", device.String())
+    // echo starts with the hotkey
+    assert.Equal(t, "\n--- local echo on ---\n\x14c\n--- board reset ---\n\n--- RTS off ---\nz", out.String())
+    assert.Equal(t, []string{"dtr off", "rts off", "dtr on", "rts on", "rts off"}, device.lines)
+
+    c = newConsole(&fakeDevice{}, out, "", false)
+    assert.Equal(t, errQuit, c.forwardRaw(strings.NewReader("x\x1dy")))
+    assert.Equal(t, errQuit, c.forwardRaw(strings.NewReader("\x03")))
+}
+
+func TestDisconnectedConsole(t *testing.T) {
+    out := &bytes.Buffer{}
+    c := newConsole(&sharedPort{}, out, "\n", false)
+    assert.Nil(t, c.forwardLines(strings.NewReader("status\n\x14d\n")))
+    assert.Equal(t, 2, strings.Count(out.String(), "--- not sent, the device is disconnected ---"))
+}
+
+type fakePort struct {
+    serial.Port
+}
+
+func TestWaitForPort(t *testing.T) {
+    reconnectInterval = time.Millisecond
+    attempts := 0
+    port := waitForPort(func() (serial.Port, error) {
+        if attempts++; attempts < 3 {
+            return nil, errors.New("no such file or directory")
+        }
+        return fakePort{}, nil
+    }, make(chan struct{}))
+    assert.Equal(t, fakePort{}, port)
+    assert.Equal(t, 3, attempts)
+
+    stop := make(chan struct{})
+    close(stop)
+    port = waitForPort(func() (serial.Port, error) {
+        return nil, errors.New("no such file or directory")
+    }, stop)
+    assert.Nil(t, port)
+}
+
+func TestCrlfWriter(t *testing.T) {
+    out := &bytes.Buffer{}
+    n, err := crlfWriter{out: out}.Write([]byte("a\nb\n"))
+    assert.Nil(t, err)
+    assert.Equal(t, 4, n)
+    assert.Equal(t, "a\r\nb\r\n", out.String())
+}
+
+func TestNewMode(t *testing.T) {
+    mode, err := NewMode(115200, 7, "Even", "2")
+    assert.Nil(t, err)
+    assert.Equal(t, &serial.Mode{BaudRate: 115200, DataBits: 7, Parity: serial.EvenParity,
+        StopBits: serial.TwoStopBits}, mode)
+    assert.Equal(t, "7E2", framing(mode))
+
+    mode, _ = NewMode(9600, 8, "none", "1")
+    assert.Equal(t, "8N1", framing(mode))
+
+    _, err = NewMode(9600, 9, "none", "1")
+    assert.NotNil(t, err)
+    _, err = NewMode(9600, 8, "both", "1")
+    assert.NotNil(t, err)
+    _, err = NewMode(9600, 8, "none", "3")
+    assert.NotNil(t, err)
+
+    _, err = GetLineEnding("CRLF")
+    assert.Nil(t, err)
+    _, err = GetLineEnding("lfcr")
+    assert.NotNil(t, err)
+}
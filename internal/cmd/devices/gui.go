@@ -0,0 +1,281 @@
+package devices
+
+import (
+    "crypto/rand"
+    "crypto/subtle"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "html"
+    "net"
+    "net/http"
+    "os"
+    "os/exec"
+    "os/signal"
+    "strconv"
+    "strings"
+    "sync"
+    "syscall"
+    "wio/pkg/log"
+    "wio/pkg/util"
+    "wio/pkg/util/sys"
+
+    "go.bug.st/serial.v1"
+)
+
+// Monitor in the browser: output is streamed to the page as server-sent events
+// and what is typed there is posted back and written to the port
+type guiServer struct {
+    page []byte
+    // address the server listens on and the secret in the url it opens, so that other pages
+    // in the browser cannot write to the device
+    host  string
+    token string
+    // guards the clients and quit
+    mutex   sync.Mutex
+    clients map[chan []byte]bool
+    quit    chan struct{}
+    // requests from the page are handled concurrently
+    consoleMutex sync.Mutex
+    console      *console
+}
+
+// Name of a line ending, e.g. "crlf"
+func lineEndingName(ending string) string {
+    for name, value := range lineEndings {
+        if value == ending {
+            return name
+        }
+    }
+    return "none"
+}
+
+// Sends data to every page that is open
+func (s *guiServer) Write(p []byte) (int, error) {
+    data := append([]byte(nil), p...)
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    for client := range s.clients {
+        select {
+        case client <- data:
+        default:
+            // the page is not keeping up, drop the data instead of blocking the port
+        }
+    }
+    return len(p), nil
+}
+
+// Random secret for the url of the monitor
+func newGuiToken() (string, error) {
+    token := make([]byte, 16)
+    if _, err := rand.Read(token); err != nil {
+        return "", err
+    }
+    return hex.EncodeToString(token), nil
+}
+
+// Whether the request comes from the page of the monitor: it has to carry the token and be
+// addressed to the listener, from the listener, which rules out other sites and dns rebinding
+func (s *guiServer) authorized(r *http.Request) bool {
+    if r.Host != s.host {
+        return false
+    }
+    if origin := r.Header.Get("Origin"); origin != "" && origin != "http://"+s.host {
+        return false
+    }
+    return subtle.ConstantTimeCompare([]byte(r.FormValue("token")), []byte(s.token)) == 1
+}
+
+// Rejects the requests that do not come from the page of the monitor
+func (s *guiServer) guard(handler http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if !s.authorized(r) {
+            http.Error(w, "forbidden", http.StatusForbidden)
+            return
+        }
+        handler(w, r)
+    }
+}
+
+func (s *guiServer) handlePage(w http.ResponseWriter, r *http.Request) {
+    if r.URL.Path != "/" {
+        http.NotFound(w, r)
+        return
+    }
+    w.Header().Set("Content-Type", "text/html; charset=utf-8")
+    w.Write(s.page)
+}
+
+func (s *guiServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+    flusher, ok := w.(http.Flusher)
+    if !ok {
+        http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+        return
+    }
+    client := make(chan []byte, 256)
+    s.mutex.Lock()
+    s.clients[client] = true
+    s.mutex.Unlock()
+    defer func() {
+        s.mutex.Lock()
+        delete(s.clients, client)
+        s.mutex.Unlock()
+    }()
+
+    w.Header().Set("Content-Type", "text/event-stream")
+    w.Header().Set("Cache-Control", "no-cache")
+    flusher.Flush()
+    for {
+        select {
+        case data := <-client:
+            text, _ := json.Marshal(string(data))
+            fmt.Fprintf(w, "data: %s\n\n", text)
+            flusher.Flush()
+        case <-r.Context().Done():
+            return
+        case <-s.quit:
+            return
+        }
+    }
+}
+
+func (s *guiServer) handleSend(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    ending, err := GetLineEnding(r.FormValue("ending"))
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    s.consoleMutex.Lock()
+    defer s.consoleMutex.Unlock()
+    if err := s.console.send([]byte(r.FormValue("text") + ending)); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
+
+func (s *guiServer) handleHotkey(w http.ResponseWriter, r *http.Request) {
+    key := r.FormValue("key")
+    if r.Method != http.MethodPost || len(key) != 1 {
+        http.Error(w, "expected a POST with a key", http.StatusBadRequest)
+        return
+    }
+    s.consoleMutex.Lock()
+    defer s.consoleMutex.Unlock()
+    if err := s.console.command(key[0]); err == errQuit {
+        s.stop()
+    } else if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
+
+func (s *guiServer) stop() {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    select {
+    case <-s.quit:
+    default:
+        close(s.quit)
+    }
+}
+
+// Opens the url with the default browser of the system
+func openBrowser(url string) error {
+    switch sys.GetOS() {
+    case sys.LINUX:
+        return exec.Command("xdg-open", url).Start()
+    case sys.DARWIN:
+        return exec.Command("open", url).Start()
+    case sys.WINDOWS:
+        return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+    }
+    return util.Error("no browser known for this operating system")
+}
+
+// Serves the monitor on localhost until it is closed from the page or interrupted
+func serveGui(portName string, options MonitorOptions, serialPort serial.Port) error {
+    page, err := sys.AssetIO.ReadFile("templates/monitor/monitor.html")
+    if err != nil {
+        return err
+    }
+    token, err := newGuiToken()
+    if err != nil {
+        return err
+    }
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        return err
+    }
+    host := listener.Addr().String()
+    replacer := strings.NewReplacer(
+        "{{PORT}}", html.EscapeString(portName),
+        "{{SETTINGS}}", strconv.Itoa(options.Mode.BaudRate)+" "+framing(options.Mode),
+        "{{LINE_ENDING}}", lineEndingName(options.LineEnding),
+        "{{TOKEN}}", token)
+
+    server := &guiServer{
+        page:    []byte(replacer.Replace(string(page))),
+        host:    host,
+        token:   token,
+        clients: map[chan []byte]bool{},
+        quit:    make(chan struct{}),
+    }
+    server.console = newConsole(serialPort, server, options.LineEnding, options.Echo)
+
+    mux := http.NewServeMux()
+    mux.HandleFunc("/", server.guard(server.handlePage))
+    mux.HandleFunc("/events", server.guard(server.handleEvents))
+    mux.HandleFunc("/send", server.guard(server.handleSend))
+    mux.HandleFunc("/hotkey", server.guard(server.handleHotkey))
+    httpServer := &http.Server{Handler: mux}
+
+    readErr := make(chan error, 1)
+    go func() {
+        buffer := make([]byte, 256)
+        for {
+            n, err := serialPort.Read(buffer)
+            if err != nil || n == 0 {
+                readErr <- err
+                server.stop()
+                return
+            }
+            server.Write(buffer[:n])
+        }
+    }()
+
+    signals := make(chan os.Signal, 1)
+    signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+    defer signal.Stop(signals)
+    go func() {
+        select {
+        case <-signals:
+            server.stop()
+        case <-server.quit:
+        }
+        httpServer.Close()
+    }()
+
+    url := "http://" + host + "/?token=" + token
+    log.Info(log.Cyan, "Wio Serial Monitor")
+    log.Info(log.Yellow, "  @  ")
+    log.Info(log.Cyan, portName)
+    log.Info(log.Yellow, "  @  ")
+    log.Infoln(log.Cyan, url)
+    log.Infoln(log.Cyan, "--- Quit: Ctrl+C or from the page ---")
+    if err := openBrowser(url); err != nil {
+        log.Warnln("could not open a browser, open %s instead", url)
+    }
+
+    if err := httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+        return err
+    }
+    log.Infoln("\n--- exit ---")
+    select {
+    case err := <-readErr:
+        return err
+    default:
+        return nil
+    }
+}
@@ -0,0 +1,40 @@
+package devices
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestGuiGuard(t *testing.T) {
+    device := &fakeDevice{}
+    server := &guiServer{host: "127.0.0.1:4000", token: "secret", quit: make(chan struct{})}
+    server.console = newConsole(device, server, "\n", false)
+    handler := server.guard(server.handleSend)
+
+    send := func(target string, token string, origin string) int {
+        request := httptest.NewRequest(http.MethodPost, target,
+            strings.NewReader("text=hi&ending=lf&token="+token))
+        request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+        if origin != "" {
+            request.Header.Set("Origin", origin)
+        }
+        recorder := httptest.NewRecorder()
+        handler(recorder, request)
+        return recorder.Code
+    }
+
+    assert.Equal(t, http.StatusOK, send("http://127.0.0.1:4000/send", "secret", "http://127.0.0.1:4000"))
+    assert.Equal(t, http.StatusOK, send("http://127.0.0.1:4000/send", "secret", ""))
+    assert.Equal(t, "hi\nhi\n", device.String())
+
+    assert.Equal(t, http.StatusForbidden, send("http://127.0.0.1:4000/send", "", ""))
+    assert.Equal(t, http.StatusForbidden, send("http://127.0.0.1:4000/send", "wrong", ""))
+    assert.Equal(t, http.StatusForbidden, send("http://127.0.0.1:4000/send", "secret", "http://example.com"))
+    // a name that resolves to the listener, as with dns rebinding
+    assert.Equal(t, http.StatusForbidden, send("http://rebound.example.com:4000/send", "secret", ""))
+    assert.Equal(t, "hi\nhi\n", device.String())
+}
@@ -0,0 +1,57 @@
+package devices
+
+import (
+    "os"
+    "runtime"
+    "syscall"
+    "wio/pkg/util"
+
+    "go.bug.st/serial.v1"
+)
+
+// Serial port that can be closed while it is being read. The serial library does not allow
+// that, so the device is read through a file of its own, which the runtime wakes up when it
+// is closed, and only configured through the serial port
+type interruptiblePort struct {
+    serial.Port
+    file *os.File
+}
+
+// Opens the serial port at the given baud rate with 8N1 framing, so that a reader blocked
+// on it returns once it is closed
+func OpenInterruptiblePort(portName string, baud int) (serial.Port, error) {
+    mode, _ := NewMode(baud, 8, "none", "1")
+    return OpenInterruptiblePortMode(portName, mode)
+}
+
+// Opens the serial port with the given settings, so that a reader blocked on it returns once
+// it is closed. Network ports already behave like this. On Windows a serial port can only be
+// opened once, so it is read as usual
+func OpenInterruptiblePortMode(portName string, mode *serial.Mode) (serial.Port, error) {
+    if isNetworkPort(portName) || runtime.GOOS == "windows" {
+        return OpenPortMode(portName, mode)
+    }
+    // opened first since the serial port takes exclusive access to the device
+    file, err := os.OpenFile(portName, os.O_RDWR|syscall.O_NOCTTY, 0)
+    if err != nil {
+        return nil, util.Error("%s port is not valid or cannot be used: %s", portName, err.Error())
+    }
+    port, err := OpenPortMode(portName, mode)
+    if err != nil {
+        file.Close()
+        return nil, err
+    }
+    return &interruptiblePort{Port: port, file: file}, nil
+}
+
+func (p *interruptiblePort) Read(data []byte) (int, error) {
+    return p.file.Read(data)
+}
+
+func (p *interruptiblePort) Close() error {
+    fileErr := p.file.Close()
+    if err := p.Port.Close(); err != nil {
+        return err
+    }
+    return fileErr
+}
@@ -0,0 +1,179 @@
+package devices
+
+import (
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "io"
+    "os"
+    "strings"
+    "wio/internal/toolchain"
+    "wio/pkg/log"
+    "wio/pkg/util"
+
+    "github.com/urfave/cli"
+)
+
+const (
+    formatText = "text"
+    formatJson = "json"
+    formatCsv  = "csv"
+)
+
+// Serial port with the board detected on it, as written in the json and csv formats
+type portInfo struct {
+    Port         string   `json:"port"`
+    Description  string   `json:"description"`
+    Hwid         string   `json:"hwid"`
+    Manufacturer string   `json:"manufacturer"`
+    SerialNumber string   `json:"serial_number"`
+    Vid          string   `json:"vid"`
+    Pid          string   `json:"pid"`
+    Product      string   `json:"product"`
+    Boards       []string `json:"boards"`
+    Bridge       string   `json:"bridge"`
+}
+
+var portInfoHeader = []string{
+    "port", "description", "hwid", "manufacturer", "serial_number", "vid", "pid", "product", "boards", "bridge",
+}
+
+func newPortInfo(port toolchain.SerialPort) portInfo {
+    return portInfo{
+        Port:         port.Port,
+        Description:  port.Description,
+        Hwid:         port.Hwid,
+        Manufacturer: port.Manufacturer,
+        SerialNumber: port.SerialNumber,
+        Vid:          port.Vid,
+        Pid:          port.Pid,
+        Product:      port.Product,
+        Boards:       toolchain.DetectBoards(port),
+        Bridge:       toolchain.SerialBridge(port),
+    }
+}
+
+// Fields in the order of the csv header, boards are separated by semicolons
+func (info portInfo) record() []string {
+    return []string{
+        info.Port, info.Description, info.Hwid, info.Manufacturer, info.SerialNumber,
+        info.Vid, info.Pid, info.Product, strings.Join(info.Boards, ";"), info.Bridge,
+    }
+}
+
+func checkFormat(format string) error {
+    switch format {
+    case formatText, formatJson, formatCsv:
+        return nil
+    }
+    return util.Error("format [%s] is not supported, use text, json or csv", format)
+}
+
+// Writes the ports as a json array
+func writePortsJson(out io.Writer, ports []toolchain.SerialPort) error {
+    infos := make([]portInfo, 0, len(ports))
+    for _, port := range ports {
+        infos = append(infos, newPortInfo(port))
+    }
+    data, err := json.MarshalIndent(infos, "", "  ")
+    if err != nil {
+        return err
+    }
+    _, err = out.Write(append(data, '\n'))
+    return err
+}
+
+// Writes the ports as csv with a header row
+func writePortsCsv(out io.Writer, ports []toolchain.SerialPort) error {
+    writer := csv.NewWriter(out)
+    if err := writer.Write(portInfoHeader); err != nil {
+        return err
+    }
+    for _, port := range ports {
+        if err := writer.Write(newPortInfo(port).record()); err != nil {
+            return err
+        }
+    }
+    writer.Flush()
+    return writer.Error()
+}
+
+// Names of the boards matching the usb ids of the device on the port
+func describeBoard(port toolchain.SerialPort) string {
+    names := make([]string, 0, 2)
+    for _, board := range toolchain.DetectBoards(port) {
+        if avrBoard, err := toolchain.GetAvrBoard(board); err == nil {
+            names = append(names, fmt.Sprintf("%s (%s)", avrBoard.Name, board))
+        }
+    }
+    if len(names) > 0 {
+        return strings.Join(names, ", ")
+    }
+    if bridge := toolchain.SerialBridge(port); bridge != "" {
+        return fmt.Sprintf("unknown, %s usb serial adapter", bridge)
+    }
+    return "unknown"
+}
+
+func logPorts(ports []toolchain.SerialPort, total int, basic bool) {
+    log.Info(log.Cyan, "Num of ports: ")
+    log.Infoln("%d\n", total)
+
+    for _, port := range ports {
+        log.Infoln(log.Yellow, port.Port)
+
+        if !basic {
+            log.Info(log.Cyan, "Board:            ")
+            log.Infoln(describeBoard(port))
+            log.Info(log.Cyan, "Product:          ")
+            log.Infoln(port.Description)
+            log.Info(log.Cyan, "Manufacturer:     ")
+            log.Infoln(port.Manufacturer)
+            log.Info(log.Cyan, "Serial Number:    ")
+            log.Infoln(port.SerialNumber)
+            log.Info(log.Cyan, "Hwid:             ")
+            log.Infoln(port.Hwid)
+            log.Info(log.Cyan, "Vid:              ")
+            log.Infoln(port.Vid)
+            log.Info(log.Cyan, "Pid:              ")
+            log.Infoln(port.Pid)
+        }
+
+        log.Infoln()
+    }
+}
+
+// Provides information abouts ports
+func handlePorts(context *cli.Context) error {
+    format := context.String("format")
+    if err := checkFormat(format); err != nil {
+        return err
+    }
+    ports, err := toolchain.GetPorts()
+    if err != nil {
+        return err
+    }
+
+    // ports without a usb device are closed and only shown with --show-all
+    shown := make([]toolchain.SerialPort, 0, len(ports.Ports))
+    numOpenPorts := 0
+    for _, port := range ports.Ports {
+        if port.IsUsb() {
+            numOpenPorts++
+        }
+        if port.IsUsb() || context.Bool("show-all") {
+            shown = append(shown, port)
+        }
+    }
+
+    switch format {
+    case formatJson:
+        return writePortsJson(os.Stdout, shown)
+    case formatCsv:
+        return writePortsCsv(os.Stdout, shown)
+    }
+    logPorts(shown, len(ports.Ports), context.Bool("basic"))
+    log.Info(log.Cyan, "Num of open ports: ")
+    log.Infoln("%d", numOpenPorts)
+    return nil
+}
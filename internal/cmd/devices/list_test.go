@@ -0,0 +1,60 @@
+package devices
+
+import (
+    "bytes"
+    "encoding/json"
+    "testing"
+    "time"
+    "wio/internal/toolchain"
+
+    "github.com/stretchr/testify/assert"
+)
+
+var (
+    unoPort = toolchain.SerialPort{Port: "/dev/ttyACM0", Description: "ttyACM0", Vid: "2341", Pid: "0043",
+        SerialNumber: "7583", Manufacturer: "Arduino, LLC"}
+    clonePort = toolchain.SerialPort{Port: "/dev/ttyUSB0", Description: "USB2.0-Serial", Vid: "1a86",
+        Pid: "7523", Product: "USB2.0-Serial"}
+)
+
+func TestWritePorts(t *testing.T) {
+    out := &bytes.Buffer{}
+    assert.Nil(t, writePortsCsv(out, []toolchain.SerialPort{unoPort, clonePort}))
+    assert.Equal(t, "port,description,hwid,manufacturer,serial_number,vid,pid,product,boards,bridge\n"+
+        "/dev/ttyACM0,ttyACM0,,\"Arduino, LLC\",7583,2341,0043,,uno,\n"+
+        "/dev/ttyUSB0,USB2.0-Serial,,,,1a86,7523,USB2.0-Serial,,CH340\n", out.String())
+
+    out.Reset()
+    assert.Nil(t, writePortsJson(out, []toolchain.SerialPort{clonePort}))
+    var infos []map[string]interface{}
+    assert.Nil(t, json.Unmarshal(out.Bytes(), &infos))
+    assert.Equal(t, 1, len(infos))
+    assert.Equal(t, "/dev/ttyUSB0", infos[0]["port"])
+    assert.Equal(t, []interface{}{}, infos[0]["boards"])
+    assert.Equal(t, "CH340", infos[0]["bridge"])
+
+    assert.Nil(t, checkFormat("csv"))
+    assert.NotNil(t, checkFormat("xml"))
+}
+
+func TestDiffPorts(t *testing.T) {
+    now := time.Date(2018, 9, 25, 12, 0, 0, 0, time.UTC)
+    assert.Equal(t, 0, len(diffPorts([]toolchain.SerialPort{unoPort}, []toolchain.SerialPort{unoPort}, now)))
+
+    events := diffPorts([]toolchain.SerialPort{unoPort}, []toolchain.SerialPort{clonePort}, now)
+    assert.Equal(t, 2, len(events))
+    assert.Equal(t, eventAdd, events[0].Event)
+    assert.Equal(t, "/dev/ttyUSB0", events[0].Port)
+    assert.Equal(t, eventRemove, events[1].Event)
+    assert.Equal(t, "/dev/ttyACM0", events[1].Port)
+
+    // a different device showing up on the same port
+    reset := unoPort
+    reset.Pid = "0001"
+    events = diffPorts([]toolchain.SerialPort{unoPort}, []toolchain.SerialPort{reset}, now)
+    assert.Equal(t, 2, len(events))
+
+    data, err := json.Marshal(events[0])
+    assert.Nil(t, err)
+    assert.Contains(t, string(data), `"event":"add","time":"2018-09-25T12:00:00Z","port":"/dev/ttyACM0"`)
+}
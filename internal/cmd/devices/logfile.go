@@ -0,0 +1,130 @@
+package devices
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "wio/pkg/log"
+    "wio/pkg/util"
+)
+
+// Log file that is renamed to <path>.1 once it reaches the maximum size, with older logs
+// moving to <path>.2 and so on. Only the given number of old logs is kept
+type rotatingFile struct {
+    path    string
+    maxSize int64
+    backups int
+    file    *os.File
+    size    int64
+}
+
+// Parses a size such as "512K", "10M" or "1G", plain numbers are bytes
+func parseByteSize(value string) (int64, error) {
+    value = strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(value)), "B")
+    multiplier := int64(1)
+    if n := len(value); n > 0 {
+        switch value[n-1] {
+        case 'K':
+            multiplier = 1 << 10
+        case 'M':
+            multiplier = 1 << 20
+        case 'G':
+            multiplier = 1 << 30
+        }
+        if multiplier > 1 {
+            value = value[:n-1]
+        }
+    }
+    size, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+    if err != nil || size <= 0 {
+        return 0, util.Error("invalid size, use a number of bytes or one like 512K or 10M")
+    }
+    return size * multiplier, nil
+}
+
+// Opens the log for appending, a maximum size of 0 turns rotation off
+func openRotatingFile(path string, maxSize int64, backups int) (*rotatingFile, error) {
+    if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+        return nil, err
+    }
+    f := &rotatingFile{path: path, maxSize: maxSize, backups: backups}
+    if err := f.open(); err != nil {
+        return nil, err
+    }
+    return f, nil
+}
+
+func (f *rotatingFile) open() error {
+    file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+    if err != nil {
+        return err
+    }
+    info, err := file.Stat()
+    if err != nil {
+        file.Close()
+        return err
+    }
+    f.file = file
+    f.size = info.Size()
+    return nil
+}
+
+func (f *rotatingFile) backupPath(index int) string {
+    return fmt.Sprintf("%s.%d", f.path, index)
+}
+
+// Moves the log to the first backup and starts a new one. The log is opened again even if it
+// could not be moved, so logging goes on in the same file
+func (f *rotatingFile) rotate() error {
+    f.file.Close()
+    err := f.moveToBackup()
+    if openErr := f.open(); openErr != nil {
+        f.file = nil
+        return openErr
+    }
+    return err
+}
+
+func (f *rotatingFile) moveToBackup() error {
+    if f.backups == 0 {
+        return os.Remove(f.path)
+    }
+    os.Remove(f.backupPath(f.backups))
+    for i := f.backups - 1; i >= 1; i-- {
+        if _, err := os.Stat(f.backupPath(i)); err == nil {
+            if err := os.Rename(f.backupPath(i), f.backupPath(i+1)); err != nil {
+                return err
+            }
+        }
+    }
+    return os.Rename(f.path, f.backupPath(1))
+}
+
+// Writes are never split between two files, a log only goes over the maximum
+// size if a single write is bigger than it
+func (f *rotatingFile) Write(p []byte) (int, error) {
+    if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+        if err := f.rotate(); err != nil {
+            // rotation is turned off so the error is only reported once
+            f.maxSize = 0
+            log.Warnln("failed to rotate %s, logging goes on without rotating: %s", f.path, err.Error())
+        }
+    }
+    if f.file == nil {
+        if err := f.open(); err != nil {
+            return 0, err
+        }
+    }
+    n, err := f.file.Write(p)
+    f.size += int64(n)
+    return n, err
+}
+
+func (f *rotatingFile) Close() error {
+    if f.file == nil {
+        return nil
+    }
+    return f.file.Close()
+}
@@ -8,16 +8,21 @@ package devices
 
 import (
     "fmt"
+    "io"
     "os"
     "os/signal"
+    "regexp"
     "strings"
     "syscall"
+    "time"
     "wio/internal/toolchain"
+    "wio/internal/types"
     "wio/pkg/log"
     "wio/pkg/util"
 
     "github.com/urfave/cli"
     "go.bug.st/serial.v1"
+    "golang.org/x/crypto/ssh/terminal"
 )
 
 type Devices struct {
@@ -33,138 +38,358 @@ func (devices Devices) GetContext() *cli.Context {
 const (
     LIST    = 0
     MONITOR = 1
+    WATCH   = 2
+    PLOT    = 3
+    SERVE   = 4
 )
 
 // Runs the build command when cli build option is provided
 func (devices Devices) Execute() error {
     switch devices.Type {
     case MONITOR:
-        return HandleMonitor(devices.Context.Int("baud"), devices.Context.IsSet("port"), devices.Context.String("port"))
+        options, err := GetMonitorOptions(devices.Context)
+        if err != nil {
+            return err
+        }
+        return HandleMonitor(options)
     case LIST:
-        return handlePorts(devices.Context.Bool("basic"), devices.Context.Bool("show-all"))
+        return handlePorts(devices.Context)
+    case WATCH:
+        return handleWatch(devices.Context)
+    case PLOT:
+        return handlePlot(devices.Context)
+    case SERVE:
+        return handleServe(devices.Context)
     default:
         return util.Error("invalid device command")
     }
 }
 
-// Provides information abouts ports
-func handlePorts(basic bool, showAll bool) error {
-    ports, err := toolchain.GetPorts()
+// Settings of the serial monitor
+type MonitorOptions struct {
+    // port to open, found automatically if empty
+    Port string
+    Mode *serial.Mode
+    // appended to every line sent to the device
+    LineEnding string
+    // send keys as they are typed instead of line by line
+    Raw  bool
+    Echo bool
+    Gui  bool
+    // wait for the device to come back after it disconnects, e.g. during an upload
+    Reconnect bool
+
+    Timestamp bool
+    Hex       bool
+    // lines matching the filter are highlighted, shown alone or hidden depending on the mode
+    Filter     *regexp.Regexp
+    FilterMode string
+    // everything read is also written to the log, which is rotated once it reaches the maximum size
+    LogFile       string
+    LogMaxSize    int64
+    LogMaxBackups int
+}
+
+// Target named with --target, or the default one, of the project in the current directory.
+// Nil if there is no project, in which case only the flags are used
+func monitorTarget(context *cli.Context) (types.Target, error) {
+    name := context.String("target")
+    directory, err := os.Getwd()
     if err != nil {
-        return err
+        return nil, err
+    }
+    config, err := types.ReadWioConfig(directory)
+    if err != nil {
+        if name != "" {
+            return nil, err
+        }
+        return nil, nil
+    }
+    if name == "" {
+        name = config.GetInfo().GetOptions().GetDefault()
     }
+    target, exists := config.GetTargets()[name]
+    if !exists {
+        if context.IsSet("target") {
+            return nil, util.Error("unrecognized target %s", name)
+        }
+        return nil, nil
+    }
+    return target, nil
+}
 
-    log.Info(log.Cyan, "Num of ports: ")
-    log.Infoln("%d\n", len(ports.Ports))
+// Monitor settings from the flags of a command, with the monitor and upload settings of the
+// target used for the ones not given
+func GetMonitorOptions(context *cli.Context) (MonitorOptions, error) {
+    options := MonitorOptions{
+        Raw:           context.Bool("raw"),
+        Echo:          context.Bool("echo"),
+        Gui:           context.Bool("gui"),
+        Reconnect:     context.Bool("reconnect"),
+        Timestamp:     context.Bool("timestamp"),
+        Hex:           context.Bool("hex"),
+        FilterMode:    context.String("filter-mode"),
+        LogFile:       context.String("log-file"),
+        LogMaxBackups: context.Int("log-backups"),
+    }
+    target, err := monitorTarget(context)
+    if err != nil {
+        return options, err
+    }
+    baud := context.Int("baud")
+    ending := context.String("line-ending")
+    if target != nil {
+        options.Port = target.GetMonitor().GetPort()
+        if upload := target.GetUpload().GetPort(); options.Port == "" && !strings.Contains(upload, ",") {
+            options.Port = upload
+        }
+        if value := target.GetMonitor().GetBaud(); value > 0 && !context.IsSet("baud") {
+            baud = value
+        }
+        if value := target.GetMonitor().GetLineEnding(); value != "" && !context.IsSet("line-ending") {
+            ending = value
+        }
+    }
+    if context.IsSet("port") {
+        options.Port = context.String("port")
+    }
 
-    numOpenPorts := 0
-    for _, port := range ports.Ports {
-        if port.Product != "None" {
-            numOpenPorts++
+    options.Mode, err = NewMode(baud, context.Int("data-bits"), context.String("parity"),
+        context.String("stop-bits"))
+    if err != nil {
+        return options, err
+    }
+    if options.LineEnding, err = GetLineEnding(ending); err != nil {
+        return options, err
+    }
+    if filter := context.String("filter"); filter != "" {
+        if options.Filter, err = regexp.Compile(filter); err != nil {
+            return options, util.Error("invalid filter: %s", err.Error())
+        }
+        if err := checkFilterMode(options.FilterMode); err != nil {
+            return options, err
+        }
+    }
+    if options.Reconnect && options.Gui {
+        return options, util.Error("the monitor in the browser cannot reconnect, leave out --reconnect or --gui")
+    }
+    if options.Gui && (options.Timestamp || options.Hex || options.Filter != nil || options.LogFile != "") {
+        return options, util.Error("the monitor in the browser shows the output as it is, leave out " +
+            "--timestamp, --hex, --filter and --log-file or --gui")
+    }
+    if options.LogFile != "" {
+        if options.LogMaxSize, err = parseByteSize(context.String("log-max-size")); err != nil {
+            return options, err
         }
+    }
+    return options, nil
+}
 
-        if port.Product == "None" && !showAll {
-            continue
+// Short form of the serial settings, e.g. 8N1
+func framing(mode *serial.Mode) string {
+    parity := "N"
+    for name, value := range parities {
+        if value == mode.Parity {
+            parity = strings.ToUpper(name[:1])
+        }
+    }
+    for name, value := range stopBits {
+        if value == mode.StopBits {
+            return fmt.Sprintf("%d%s%s", mode.DataBits, parity, name)
         }
+    }
+    return fmt.Sprintf("%d%s", mode.DataBits, parity)
+}
 
-        log.Infoln(log.Yellow, port.Port)
+// How long the device has to be quiet before a line held back for the filter is printed
+const idleFlush = 200 * time.Millisecond
 
-        if !basic {
-            log.Info(log.Cyan, "Product:          ")
-            log.Infoln(port.Description)
-            log.Info(log.Cyan, "Manufacturer:     ")
-            log.Infoln(port.Manufacturer)
-            log.Info(log.Cyan, "Serial Number:    ")
-            log.Infoln(port.SerialNumber)
-            log.Info(log.Cyan, "Hwid:             ")
-            log.Infoln(port.Hwid)
-            log.Info(log.Cyan, "Vid:              ")
-            log.Infoln(port.Vid)
+// Reads from the port in a goroutine and hands every chunk to handle until reading fails or
+// stop is closed. idle is called when nothing was read for a while. Returns io.EOF if the
+// port was closed by the device. The goroutine ends once the caller closes the port
+func readPort(port io.Reader, stop <-chan struct{}, handle func([]byte), idle func()) error {
+    chunks := make(chan []byte)
+    errs := make(chan error, 1)
+    go func() {
+        buffer := make([]byte, 256)
+        for {
+            n, err := port.Read(buffer)
+            if err == nil && n == 0 {
+                err = io.EOF
+            }
+            if err != nil {
+                errs <- err
+                return
+            }
+            select {
+            case chunks <- append([]byte(nil), buffer[:n]...):
+            case <-stop:
+                return
+            }
         }
+    }()
 
-        log.Infoln()
+    timer := time.NewTimer(idleFlush)
+    defer timer.Stop()
+    for {
+        select {
+        case chunk := <-chunks:
+            handle(chunk)
+            if !timer.Stop() {
+                select {
+                case <-timer.C:
+                default:
+                }
+            }
+            timer.Reset(idleFlush)
+        case <-timer.C:
+            idle()
+            timer.Reset(idleFlush)
+        case err := <-errs:
+            return err
+        case <-stop:
+            return nil
+        }
     }
-
-    log.Info(log.Cyan, "Num of open ports: ")
-    log.Infoln("%d", numOpenPorts)
-    return nil
 }
 
-// Opens monitor to see serial data
-func HandleMonitor(baud int, portDefined bool, portProvided string) error {
-    var port *toolchain.SerialPort
+// Channel closed on Ctrl+C, on SIGTERM or once quit is closed
+func stopChannel(quit <-chan struct{}) (<-chan struct{}, func()) {
+    stop := make(chan struct{})
+    signals := make(chan os.Signal, 1)
+    signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+    done := make(chan struct{})
+    go func() {
+        select {
+        case <-signals:
+        case <-quit:
+        case <-done:
+        }
+        close(stop)
+    }()
+    return stop, func() {
+        signal.Stop(signals)
+        close(done)
+    }
+}
 
+// Port given or, if empty, the one an Arduino is connected to
+func findPort(portName string) (string, error) {
+    if portName != "" {
+        return portName, nil
+    }
     ports, err := toolchain.GetPorts()
     if err != nil {
-        port = nil
-    } else {
-        port = toolchain.GetArduinoPort(ports)
+        return "", err
+    }
+    port := toolchain.GetArduinoPort(ports)
+    if port == nil {
+        return "", util.Error("failed to automatically detect AVR port")
     }
+    return port.Port, nil
+}
 
-    portToUse := portProvided
+// Opens monitor to see serial data and send data to the device
+func HandleMonitor(options MonitorOptions) error {
+    portToUse, err := findPort(options.Port)
+    if err != nil {
+        return err
+    }
 
-    if !portDefined {
-        if port == nil {
-            return util.Error("failed to automatically detect AVR port")
-        } else {
-            portToUse = port.Port
-        }
+    open := func() (serial.Port, error) {
+        return OpenPortMode(portToUse, options.Mode)
+    }
+    serialPort, err := open()
+    if err != nil && !options.Reconnect {
+        return err
     }
 
-    // Open the first serial port detected at 9600bps N81
-    mode := &serial.Mode{
-        BaudRate: baud,
-        Parity:   serial.NoParity,
-        DataBits: 8,
-        StopBits: serial.OneStopBit,
+    if options.Gui {
+        defer serialPort.Close()
+        return serveGui(portToUse, options, serialPort)
     }
-    serialPort, err := serial.Open(portToUse, mode)
-    if err != nil {
-        if strings.Contains(err.Error(), "Invalid serial port") {
-            return util.Error("invalid baud rate")
+
+    var logFile *rotatingFile
+    if options.LogFile != "" {
+        if logFile, err = openRotatingFile(options.LogFile, options.LogMaxSize, options.LogMaxBackups); err != nil {
+            return err
         }
+        defer logFile.Close()
     }
 
-    defer serialPort.Close()
-
     log.Info(log.Cyan, "Wio Serial Monitor")
     log.Info(log.Yellow, "  @  ")
     log.Info(log.Cyan, portToUse)
     log.Info(log.Yellow, "  @  ")
-    log.Infoln(log.Cyan, "%d", baud)
-    log.Infoln(log.Cyan, "--- Quit: Ctrl+C ---")
+    log.Infoln(log.Cyan, "%d %s", options.Mode.BaudRate, framing(options.Mode))
+    if logFile != nil {
+        log.Info(log.Cyan, "Logging to ")
+        log.Infoln(log.Magenta, options.LogFile)
+    }
+    log.Infoln(log.Cyan, "--- Quit: Ctrl+C, Hotkeys: Ctrl+T h ---")
 
-    c := make(chan os.Signal)
-    signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-    go func() {
-        <-c
-        log.Infoln("\n--- exit ---")
-        os.Exit(1)
-    }()
+    // in raw mode the keys go straight to the device and the terminal is restored on exit
+    var output io.Writer = os.Stdout
+    stdin := int(os.Stdin.Fd())
+    if options.Raw && terminal.IsTerminal(stdin) {
+        state, err := terminal.MakeRaw(stdin)
+        if err != nil {
+            return err
+        }
+        defer terminal.Restore(stdin, state)
+        output = crlfWriter{out: os.Stdout}
+    }
 
-    // When invalid port is read, serial library panics and hence that panic needs to be caught
-    defer func() {
-        if recover() != nil {
-            log.Errln("%s port is not valid or cannot be used", portToUse)
-            os.Exit(1)
+    quit := make(chan struct{})
+    shared := &sharedPort{port: serialPort}
+    defer shared.close()
+    console := newConsole(shared, output, options.LineEnding, options.Echo)
+    go func() {
+        var err error
+        if options.Raw {
+            err = console.forwardRaw(os.Stdin)
+        } else {
+            err = console.forwardLines(os.Stdin)
+        }
+        if err == errQuit {
+            close(quit)
+        } else if err != nil {
+            log.Errln("failed to send to %s: %s", portToUse, err.Error())
         }
     }()
+    stop, release := stopChannel(quit)
+    defer release()
 
-    // Read and print the response
-    buff := make([]byte, 100)
+    var out *printer
+    if logFile != nil {
+        out = newPrinter(output, logFile, options)
+    } else {
+        out = newPrinter(output, nil, options)
+    }
     for {
-        // Reads up to 100 bytes
-        n, err := serialPort.Read(buff)
-
-        if err != nil {
-            return err
-            break
+        if serialPort == nil {
+            fmt.Fprintf(output, "--- waiting for %s ---\n", portToUse)
+            if serialPort = waitForPort(open, stop); serialPort == nil {
+                break
+            }
+            shared.set(serialPort)
+            fmt.Fprintf(output, "--- %s connected ---\n", portToUse)
         }
-        if n == 0 {
-            fmt.Println("\nEOF")
+        err = readPort(serialPort, stop, out.write, out.flush)
+        out.flush()
+        if err == nil {
             break
+        } else if !options.Reconnect {
+            if err == io.EOF {
+                fmt.Fprintln(output, "\n--- EOF ---")
+                return nil
+            }
+            return util.Error("%s port is not valid or cannot be used: %s", portToUse, err.Error())
         }
-        fmt.Printf("%v", string(buff[:n]))
+        shared.close()
+        serialPort = nil
+        fmt.Fprintf(output, "\n--- %s disconnected ---\n", portToUse)
     }
+    fmt.Fprintln(output, "\n--- exit ---")
     return nil
 }
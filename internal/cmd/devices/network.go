@@ -0,0 +1,138 @@
+package devices
+
+import (
+    "net"
+    "strings"
+    "sync"
+    "time"
+    "wio/pkg/util"
+
+    "go.bug.st/serial.v1"
+)
+
+// Ports shared over the network, by `wio devices serve` or servers like ser2net
+const (
+    tcpScheme     = "tcp://"
+    rfc2217Scheme = "rfc2217://"
+)
+
+const dialTimeout = 5 * time.Second
+
+// Whether the port is reached over the network, e.g. tcp://lab-pc:4000 or rfc2217://lab-pc:4000
+func isNetworkPort(portName string) bool {
+    return strings.HasPrefix(portName, tcpScheme) || strings.HasPrefix(portName, rfc2217Scheme)
+}
+
+// Serial port on another machine. Over tcp only data is sent and the serial settings are
+// the ones of the server. Over RFC 2217 the settings and the control lines are sent as well
+type networkPort struct {
+    conn    net.Conn
+    rfc2217 bool
+    // only used by the reader
+    decoder telnetDecoder
+    // guards writes, which come from the user and from the answers of the reader
+    mutex   sync.Mutex
+    options *telnetOptions
+}
+
+func openNetworkPort(portName string, mode *serial.Mode) (serial.Port, error) {
+    port := &networkPort{rfc2217: strings.HasPrefix(portName, rfc2217Scheme)}
+    address := strings.TrimPrefix(strings.TrimPrefix(portName, tcpScheme), rfc2217Scheme)
+    conn, err := net.DialTimeout("tcp", address, dialTimeout)
+    if err != nil {
+        return nil, util.Error("%s port is not valid or cannot be used: %s", portName, err.Error())
+    }
+    port.conn = conn
+    if port.rfc2217 {
+        port.options = newTelnetOptions([]byte{optionBinary, optionComPort}, []byte{optionBinary, optionSGA})
+        message := port.options.request([]byte{optionBinary, optionComPort}, []byte{optionBinary})
+        if _, err := conn.Write(append(message, comMode(mode)...)); err != nil {
+            conn.Close()
+            return nil, err
+        }
+    }
+    return port, nil
+}
+
+func (p *networkPort) send(message []byte) error {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+    _, err := p.conn.Write(message)
+    return err
+}
+
+func (p *networkPort) Read(data []byte) (int, error) {
+    if !p.rfc2217 {
+        return p.conn.Read(data)
+    }
+    for {
+        n, err := p.conn.Read(data)
+        var answers []byte
+        received := p.decoder.decode(data[:n], func(verb byte, option byte) {
+            answers = append(answers, p.options.answer(verb, option)...)
+        }, func([]byte) {
+            // the server confirming the settings, nothing to do with it
+        })
+        if len(answers) > 0 {
+            if sendErr := p.send(answers); sendErr != nil && err == nil {
+                err = sendErr
+            }
+        }
+        if len(received) > 0 || err != nil {
+            return copy(data, received), err
+        }
+    }
+}
+
+func (p *networkPort) Write(data []byte) (int, error) {
+    message := data
+    if p.rfc2217 {
+        message = escapeTelnet(data)
+    }
+    if err := p.send(message); err != nil {
+        return 0, err
+    }
+    return len(data), nil
+}
+
+// Sends a com port command, which does nothing over plain tcp
+func (p *networkPort) command(message []byte) error {
+    if !p.rfc2217 {
+        return nil
+    }
+    return p.send(message)
+}
+
+func (p *networkPort) SetMode(mode *serial.Mode) error {
+    return p.command(comMode(mode))
+}
+
+func (p *networkPort) ResetInputBuffer() error {
+    return p.command(comCommand(comPurgeData, purgeReceive))
+}
+
+func (p *networkPort) ResetOutputBuffer() error {
+    return p.command(comCommand(comPurgeData, purgeTransmit))
+}
+
+func (p *networkPort) SetDTR(dtr bool) error {
+    if dtr {
+        return p.command(comCommand(comSetControl, controlDtrOn))
+    }
+    return p.command(comCommand(comSetControl, controlDtrOff))
+}
+
+func (p *networkPort) SetRTS(rts bool) error {
+    if rts {
+        return p.command(comCommand(comSetControl, controlRtsOn))
+    }
+    return p.command(comCommand(comSetControl, controlRtsOff))
+}
+
+func (p *networkPort) GetModemStatusBits() (*serial.ModemStatusBits, error) {
+    return nil, util.Error("the modem status of a port on the network is not known")
+}
+
+func (p *networkPort) Close() error {
+    return p.conn.Close()
+}
@@ -0,0 +1,187 @@
+package devices
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "regexp"
+    "time"
+    "wio/pkg/log"
+    "wio/pkg/util"
+)
+
+const (
+    filterHighlight = "highlight"
+    filterInclude   = "include"
+    filterExclude   = "exclude"
+)
+
+const timestampFormat = "2006-01-02 15:04:05.000"
+
+// Bytes shown on each line of the hex view
+const hexWidth = 16
+
+// Checks the --filter-mode value
+func checkFilterMode(mode string) error {
+    switch mode {
+    case filterHighlight, filterInclude, filterExclude:
+        return nil
+    }
+    return util.Error("filter mode [%s] is not supported, use highlight, include or exclude", mode)
+}
+
+// Formats what is read from the device for the terminal and the log file. The log gets
+// every line, the filter only changes what the terminal shows
+type printer struct {
+    out io.Writer
+    // nil if there is no log file
+    log        io.Writer
+    timestamp  bool
+    hex        bool
+    filter     *regexp.Regexp
+    filterMode string
+    now        func() time.Time
+
+    // start of a line that is held back until it is complete, as the filter needs whole lines
+    pending []byte
+    // whether the next byte starts a new line
+    lineStart bool
+    // position in the stream shown by the hex view
+    offset int64
+}
+
+func newPrinter(out io.Writer, logFile io.Writer, options MonitorOptions) *printer {
+    return &printer{
+        out:        out,
+        log:        logFile,
+        timestamp:  options.Timestamp,
+        hex:        options.Hex,
+        filter:     options.Filter,
+        filterMode: options.FilterMode,
+        now:        time.Now,
+        lineStart:  true,
+    }
+}
+
+func (p *printer) prefix() string {
+    if !p.timestamp {
+        return ""
+    }
+    return "[" + p.now().Format(timestampFormat) + "] "
+}
+
+// Timestamps are colored on the terminal
+func coloredPrefix(prefix string) string {
+    if prefix == "" {
+        return ""
+    }
+    return log.Cyan.Sprint(prefix)
+}
+
+func (p *printer) writeLog(text string) {
+    if p.log != nil {
+        io.WriteString(p.log, text)
+    }
+}
+
+// Prints a line or part of one, which only gets the timestamp if it starts the line
+func (p *printer) printText(text []byte, filtered bool) {
+    prefix := ""
+    if p.lineStart {
+        prefix = p.prefix()
+    }
+    p.lineStart = len(text) > 0 && text[len(text)-1] == '\n'
+    p.writeLog(prefix + string(text))
+
+    if filtered {
+        matches := p.filter.Match(text)
+        switch {
+        case p.filterMode == filterInclude && !matches, p.filterMode == filterExclude && matches:
+            return
+        case p.filterMode == filterHighlight && matches:
+            io.WriteString(p.out, coloredPrefix(prefix)+log.Yellow.Sprint(string(text)))
+            return
+        }
+    }
+    io.WriteString(p.out, coloredPrefix(prefix)+string(text))
+}
+
+// One line of the hex view: offset, bytes in hex and the printable ones as text
+func hexLine(offset int64, data []byte) string {
+    line := &bytes.Buffer{}
+    fmt.Fprintf(line, "%08x ", offset)
+    for i := 0; i < hexWidth; i++ {
+        if i == hexWidth/2 {
+            line.WriteByte(' ')
+        }
+        if i < len(data) {
+            fmt.Fprintf(line, " %02x", data[i])
+        } else {
+            line.WriteString("   ")
+        }
+    }
+    line.WriteString("  |")
+    for _, b := range data {
+        if b >= 0x20 && b < 0x7f {
+            line.WriteByte(b)
+        } else {
+            line.WriteByte('.')
+        }
+    }
+    line.WriteString("|\n")
+    return line.String()
+}
+
+// Shows each chunk as it arrives so that short messages are not held back
+func (p *printer) printHex(data []byte) {
+    for len(data) > 0 {
+        n := hexWidth
+        if len(data) < n {
+            n = len(data)
+        }
+        prefix := p.prefix()
+        line := hexLine(p.offset, data[:n])
+        p.writeLog(prefix + line)
+        io.WriteString(p.out, coloredPrefix(prefix)+line)
+        p.offset += int64(n)
+        data = data[n:]
+    }
+}
+
+// Prints data read from the device
+func (p *printer) write(data []byte) {
+    if p.hex {
+        p.printHex(data)
+        return
+    }
+    if p.filter == nil {
+        // without a filter text is printed as it arrives, split so that every line gets its timestamp
+        for len(data) > 0 {
+            end := bytes.IndexByte(data, '\n') + 1
+            if end == 0 {
+                end = len(data)
+            }
+            p.printText(data[:end], false)
+            data = data[end:]
+        }
+        return
+    }
+    p.pending = append(p.pending, data...)
+    for {
+        end := bytes.IndexByte(p.pending, '\n') + 1
+        if end == 0 {
+            return
+        }
+        p.printText(p.pending[:end], true)
+        p.pending = p.pending[end:]
+    }
+}
+
+// Prints the part of a line held back for the filter, called when the device goes quiet
+// so that prompts without a new line still show up
+func (p *printer) flush() {
+    if len(p.pending) > 0 {
+        p.printText(p.pending, true)
+        p.pending = nil
+    }
+}
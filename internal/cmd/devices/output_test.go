@@ -0,0 +1,168 @@
+package devices
+
+import (
+    "bytes"
+    "errors"
+    "io"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strings"
+    "testing"
+    "time"
+    "wio/pkg/log"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func testPrinter(options MonitorOptions) (*printer, *bytes.Buffer, *bytes.Buffer) {
+    out := &bytes.Buffer{}
+    logFile := &bytes.Buffer{}
+    p := newPrinter(out, logFile, options)
+    p.now = func() time.Time {
+        return time.Date(2018, 9, 25, 13, 4, 5, 6000000, time.UTC)
+    }
+    return p, out, logFile
+}
+
+func TestPrinterTimestamp(t *testing.T) {
+    p, out, logFile := testPrinter(MonitorOptions{Timestamp: true})
+    p.write([]byte("boot"))
+    p.write([]byte("ed\nready\n> "))
+    p.flush()
+    expected := "[2018-09-25 13:04:05.006] booted\n[2018-09-25 13:04:05.006] ready\n[2018-09-25 13:04:05.006] > "
+    assert.Equal(t, expected, out.String())
+    assert.Equal(t, expected, logFile.String())
+}
+
+func TestPrinterFilter(t *testing.T) {
+    p, out, logFile := testPrinter(MonitorOptions{Filter: regexp.MustCompile("ERR"), FilterMode: filterExclude})
+    p.write([]byte("ok 1\nERR 2\nok"))
+    assert.Equal(t, "ok 1\n", out.String())
+    p.write([]byte(" 3\n> "))
+    assert.Equal(t, "ok 1\nok 3\n", out.String())
+    p.flush()
+    assert.Equal(t, "ok 1\nok 3\n> ", out.String())
+    assert.Equal(t, "ok 1\nERR 2\nok 3\n> ", logFile.String())
+
+    p, out, _ = testPrinter(MonitorOptions{Filter: regexp.MustCompile("^temp"), FilterMode: filterInclude})
+    p.write([]byte("temp 21\nhumidity 40\ntemp 22\n"))
+    assert.Equal(t, "temp 21\ntemp 22\n", out.String())
+
+    assert.Nil(t, checkFilterMode("highlight"))
+    assert.NotNil(t, checkFilterMode("hide"))
+}
+
+func TestPrinterHex(t *testing.T) {
+    p, out, _ := testPrinter(MonitorOptions{Hex: true})
+    p.write([]byte("Hello, world!\n\x00\x01\xff"))
+    p.write([]byte("AB"))
+    assert.Equal(t,
+        "00000000  48 65 6c 6c 6f 2c 20 77  6f 72 6c 64 21 0a 00 01  |Hello, world!...|\n"+
+            "00000010  ff                                                |.|\n"+
+            "00000011  41 42                                             |AB|\n", out.String())
+}
+
+type errorReader struct{}
+
+func (errorReader) Read([]byte) (int, error) {
+    return 0, errors.New("device disconnected")
+}
+
+func TestReadPort(t *testing.T) {
+    reader, writer := io.Pipe()
+    stop := make(chan struct{})
+    received := &bytes.Buffer{}
+    done := make(chan error)
+    go func() {
+        done <- readPort(reader, stop, func(data []byte) {
+            received.Write(data)
+            if bytes.HasSuffix(received.Bytes(), []byte("!")) {
+                close(stop)
+            }
+        }, func() {})
+    }()
+    writer.Write([]byte("hello"))
+    writer.Write([]byte(" world!"))
+    assert.Nil(t, <-done)
+    assert.Equal(t, "hello world!", received.String())
+    writer.Close()
+
+    err := readPort(errorReader{}, make(chan struct{}), func([]byte) {}, func() {})
+    assert.Equal(t, "device disconnected", err.Error())
+
+    reader, writer = io.Pipe()
+    writer.Close()
+    assert.Equal(t, io.EOF, readPort(reader, make(chan struct{}), func([]byte) {}, func() {}))
+}
+
+func TestRotatingFile(t *testing.T) {
+    dir, err := ioutil.TempDir("", "wio-monitor")
+    assert.Nil(t, err)
+    defer os.RemoveAll(dir)
+    path := filepath.Join(dir, "logs", "serial.log")
+
+    file, err := openRotatingFile(path, 10, 2)
+    assert.Nil(t, err)
+    for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+        _, err := file.Write([]byte(line))
+        assert.Nil(t, err)
+    }
+    assert.Nil(t, file.Close())
+
+    read := func(path string) string {
+        data, _ := ioutil.ReadFile(path)
+        return string(data)
+    }
+    assert.Equal(t, "fourth\n", read(path))
+    assert.Equal(t, "third\n", read(path+".1"))
+    assert.Equal(t, "second\n", read(path+".2"))
+    _, err = os.Stat(path + ".3")
+    assert.True(t, os.IsNotExist(err))
+
+    // appends to the existing log
+    file, err = openRotatingFile(path, 100, 2)
+    assert.Nil(t, err)
+    file.Write([]byte("fifth\n"))
+    file.Close()
+    assert.Equal(t, "fourth\nfifth\n", read(path))
+}
+
+func TestRotatingFile_RenameFails(t *testing.T) {
+    dir, err := ioutil.TempDir("", "wio-monitor")
+    assert.Nil(t, err)
+    defer os.RemoveAll(dir)
+    path := filepath.Join(dir, "serial.log")
+    // a directory that is not empty can not be replaced by the log
+    assert.Nil(t, os.MkdirAll(filepath.Join(path+".1", "keep"), os.ModePerm))
+
+    file, err := openRotatingFile(path, 10, 1)
+    assert.Nil(t, err)
+    stderr := &bytes.Buffer{}
+    prevOut, prevErr := log.SetOutput(ioutil.Discard, stderr)
+    for _, line := range []string{"first\n", "second\n", "third\n"} {
+        n, err := file.Write([]byte(line))
+        assert.Nil(t, err)
+        assert.Equal(t, len(line), n)
+    }
+    log.SetOutput(prevOut, prevErr)
+    assert.Nil(t, file.Close())
+    assert.Equal(t, 1, strings.Count(stderr.String(), "failed to rotate"))
+
+    data, err := ioutil.ReadFile(path)
+    assert.Nil(t, err)
+    assert.Equal(t, "first\nsecond\nthird\n", string(data))
+}
+
+func TestParseByteSize(t *testing.T) {
+    for value, expected := range map[string]int64{"100": 100, "512K": 512 << 10, "10mb": 10 << 20, "1G": 1 << 30} {
+        size, err := parseByteSize(value)
+        assert.Nil(t, err)
+        assert.Equal(t, expected, size)
+    }
+    _, err := parseByteSize("ten")
+    assert.NotNil(t, err)
+    _, err = parseByteSize("0")
+    assert.NotNil(t, err)
+}
@@ -0,0 +1,312 @@
+package devices
+
+import (
+    "bytes"
+    "encoding/csv"
+    "fmt"
+    "io"
+    "math"
+    "os"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+    "wio/pkg/log"
+    "wio/pkg/util"
+
+    "github.com/urfave/cli"
+    "golang.org/x/crypto/ssh/terminal"
+)
+
+// Value of a field of a telemetry line
+type sample struct {
+    name  string
+    value float64
+}
+
+var (
+    // "temp:21.5", "temp = 21.5" and so on
+    pairPattern = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_.\-]*)\s*[:=]\s*([-+]?(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?)`)
+    // columns of a csv line, which may also be separated by semicolons, tabs or spaces
+    columnSeparator = regexp.MustCompile(`[,;\t ]+`)
+)
+
+// Finds the numbers on the lines of telemetry sent by a device. Lines of key:value or key=value
+// pairs give named fields. Other lines are read as csv, with the columns named by a header line
+// if the device sent one and numbered from 1 otherwise
+type telemetryParser struct {
+    header []string
+}
+
+func (p *telemetryParser) parse(line string) []sample {
+    line = strings.TrimSpace(line)
+    if line == "" {
+        return nil
+    }
+    var samples []sample
+    if pairs := pairPattern.FindAllStringSubmatch(line, -1); len(pairs) > 0 {
+        for _, pair := range pairs {
+            value, _ := strconv.ParseFloat(pair[2], 64)
+            samples = append(samples, sample{name: pair[1], value: value})
+        }
+        return samples
+    }
+
+    columns := columnSeparator.Split(strings.Trim(line, ",; "), -1)
+    for i, column := range columns {
+        value, err := strconv.ParseFloat(column, 64)
+        if err != nil {
+            continue
+        }
+        name := strconv.Itoa(i + 1)
+        if i < len(p.header) {
+            name = p.header[i]
+        }
+        samples = append(samples, sample{name: name, value: value})
+    }
+    if len(samples) == 0 {
+        p.header = columns
+    }
+    return samples
+}
+
+var (
+    sparkLevels = []rune("▁▂▃▄▅▆▇█")
+    asciiLevels = []rune("_.-:=+*#")
+)
+
+// Draws the values as one character each, scaled between the smallest and the biggest
+func sparkline(values []float64, levels []rune) string {
+    low, high := math.Inf(1), math.Inf(-1)
+    for _, value := range values {
+        low = math.Min(low, value)
+        high = math.Max(high, value)
+    }
+    line := make([]rune, len(values))
+    for i, value := range values {
+        level := 0
+        if high > low {
+            level = int((value - low) / (high - low) * float64(len(levels)-1) + 0.5)
+        }
+        line[i] = levels[level]
+    }
+    return string(line)
+}
+
+// Last values of each series, in the order the series were first seen
+type plot struct {
+    // number of values kept for each series
+    width  int
+    levels []rune
+    // series to show, all of them if empty
+    only   map[string]bool
+    names  []string
+    series map[string][]float64
+}
+
+func newPlot(width int, ascii bool, only []string) *plot {
+    p := &plot{width: width, levels: sparkLevels, only: map[string]bool{}, series: map[string][]float64{}}
+    if ascii {
+        p.levels = asciiLevels
+    }
+    for _, name := range only {
+        p.only[name] = true
+    }
+    return p
+}
+
+func (p *plot) add(samples []sample) {
+    for _, sample := range samples {
+        if len(p.only) > 0 && !p.only[sample.name] {
+            continue
+        }
+        values, exists := p.series[sample.name]
+        if !exists {
+            p.names = append(p.names, sample.name)
+        }
+        values = append(values, sample.value)
+        if len(values) > p.width {
+            values = values[len(values)-p.width:]
+        }
+        p.series[sample.name] = values
+    }
+}
+
+// One line for each series with its name, last value, chart and range
+func (p *plot) render() []string {
+    nameWidth := 0
+    for _, name := range p.names {
+        if len(name) > nameWidth {
+            nameWidth = len(name)
+        }
+    }
+    lines := make([]string, 0, len(p.names))
+    for _, name := range p.names {
+        values := p.series[name]
+        low, high := math.Inf(1), math.Inf(-1)
+        for _, value := range values {
+            low = math.Min(low, value)
+            high = math.Max(high, value)
+        }
+        lines = append(lines, fmt.Sprintf("%-*s %10.4g  %-*s  min %.4g  max %.4g", nameWidth, name,
+            values[len(values)-1], p.width, sparkline(values, p.levels), low, high))
+    }
+    return lines
+}
+
+// Writes every value decoded as a row of time, series and value
+type seriesExporter struct {
+    file *os.File
+    csv  *csv.Writer
+}
+
+func newSeriesExporter(path string) (*seriesExporter, error) {
+    file, err := os.Create(path)
+    if err != nil {
+        return nil, err
+    }
+    exporter := &seriesExporter{file: file, csv: csv.NewWriter(file)}
+    if err := exporter.csv.Write([]string{"time", "series", "value"}); err != nil {
+        file.Close()
+        return nil, err
+    }
+    return exporter, nil
+}
+
+func (e *seriesExporter) write(now time.Time, samples []sample) error {
+    for _, sample := range samples {
+        record := []string{now.Format(time.RFC3339Nano), sample.name, strconv.FormatFloat(sample.value, 'g', -1, 64)}
+        if err := e.csv.Write(record); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (e *seriesExporter) Close() error {
+    e.csv.Flush()
+    if err := e.csv.Error(); err != nil {
+        e.file.Close()
+        return err
+    }
+    return e.file.Close()
+}
+
+// Redraws the chart over the previous one on a terminal, elsewhere it is only printed
+type plotScreen struct {
+    out   io.Writer
+    live  bool
+    lines int
+}
+
+func (s *plotScreen) draw(lines []string) {
+    buffer := &bytes.Buffer{}
+    if s.live && s.lines > 0 {
+        fmt.Fprintf(buffer, "\x1b[%dA", s.lines)
+    }
+    for _, line := range lines {
+        if s.live {
+            buffer.WriteString("\r\x1b[2K")
+        }
+        buffer.WriteString(line + "\n")
+    }
+    s.out.Write(buffer.Bytes())
+    s.lines = len(lines)
+}
+
+// How often the chart is redrawn at most
+const plotRefresh = 100 * time.Millisecond
+
+// Plots the numbers sent by the device and exports them to csv if asked to
+func handlePlot(context *cli.Context) error {
+    width := context.Int("width")
+    if width <= 0 {
+        return util.Error("--width must be positive")
+    }
+    var only []string
+    for _, name := range strings.Split(context.String("series"), ",") {
+        if name = strings.TrimSpace(name); name != "" {
+            only = append(only, name)
+        }
+    }
+    portName := ""
+    if context.IsSet("port") {
+        portName = context.String("port")
+    }
+    portName, err := findPort(portName)
+    if err != nil {
+        return err
+    }
+
+    var exporter *seriesExporter
+    if path := context.String("output"); path != "" {
+        if exporter, err = newSeriesExporter(path); err != nil {
+            return err
+        }
+    }
+
+    serialPort, err := OpenPort(portName, context.Int("baud"))
+    if err != nil {
+        if exporter != nil {
+            exporter.Close()
+        }
+        return err
+    }
+    defer serialPort.Close()
+
+    log.Info(log.Cyan, "Wio Serial Plotter")
+    log.Info(log.Yellow, "  @  ")
+    log.Info(log.Cyan, portName)
+    log.Info(log.Yellow, "  @  ")
+    log.Infoln(log.Cyan, "%d", context.Int("baud"))
+    log.Infoln(log.Cyan, "--- Quit: Ctrl+C ---")
+
+    // charts are redrawn in place on a terminal and printed once at the end otherwise
+    screen := &plotScreen{out: os.Stdout, live: terminal.IsTerminal(int(os.Stdout.Fd()))}
+    plot := newPlot(width, context.Bool("ascii"), only)
+    parser := &telemetryParser{}
+    var pending []byte
+    var exportErr error
+    lastDraw := time.Time{}
+
+    redraw := func() {
+        if screen.live && time.Since(lastDraw) >= plotRefresh {
+            screen.draw(plot.render())
+            lastDraw = time.Now()
+        }
+    }
+    handle := func(data []byte) {
+        pending = append(pending, data...)
+        for {
+            end := bytes.IndexByte(pending, '\n')
+            if end < 0 {
+                break
+            }
+            samples := parser.parse(string(pending[:end]))
+            pending = pending[end+1:]
+            plot.add(samples)
+            if exporter != nil && exportErr == nil {
+                exportErr = exporter.write(time.Now(), samples)
+            }
+        }
+        redraw()
+    }
+
+    stop, release := stopChannel(nil)
+    defer release()
+    err = readPort(serialPort, stop, handle, redraw)
+    screen.draw(plot.render())
+    // rows are buffered, so errors writing them may only show up when they are flushed
+    if exporter != nil {
+        if closeErr := exporter.Close(); exportErr == nil {
+            exportErr = closeErr
+        }
+    }
+    if exportErr != nil {
+        return util.Error("failed to export to %s: %s", context.String("output"), exportErr.Error())
+    }
+    if err != nil && err != io.EOF {
+        return util.Error("%s port is not valid or cannot be used: %s", portName, err.Error())
+    }
+    return nil
+}
@@ -0,0 +1,16 @@
+package devices
+
+import (
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestSeriesExporter_Full(t *testing.T) {
+    // rows are buffered, writing to a full disk only fails once they are flushed
+    exporter, err := newSeriesExporter("/dev/full")
+    assert.Nil(t, err)
+    assert.Nil(t, exporter.write(time.Now(), []sample{{"temp", 21.5}}))
+    assert.NotNil(t, exporter.Close())
+}
@@ -0,0 +1,65 @@
+package devices
+
+import (
+    "bytes"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestTelemetryParser(t *testing.T) {
+    parser := &telemetryParser{}
+    assert.Equal(t, []sample{{"temp", 21.5}, {"humidity", 40}},
+        parser.parse("temp:21.5, humidity: 40"))
+    assert.Equal(t, []sample{{"x", -1}, {"y", 2e3}}, parser.parse("x=-1 y = 2e3\r"))
+    assert.Equal(t, []sample{{"1", 1}, {"2", 2.5}, {"3", -3}}, parser.parse("1,2.5,-3"))
+
+    assert.Nil(t, parser.parse("time,speed,rpm"))
+    assert.Equal(t, []sample{{"time", 10}, {"speed", 3.5}, {"rpm", 1200}}, parser.parse("10;3.5;1200"))
+    assert.Equal(t, []sample{{"speed", 4}}, parser.parse("n/a 4"))
+    assert.Nil(t, parser.parse("  "))
+}
+
+func TestPlot(t *testing.T) {
+    assert.Equal(t, "▁▅█▁", sparkline([]float64{0, 5, 10, 0}, sparkLevels))
+    assert.Equal(t, "___", sparkline([]float64{3, 3, 3}, asciiLevels))
+
+    p := newPlot(3, true, []string{"temp", "rpm"})
+    p.add([]sample{{"temp", 20}, {"humidity", 40}})
+    p.add([]sample{{"rpm", 1000}, {"temp", 21}})
+    p.add([]sample{{"temp", 22}})
+    p.add([]sample{{"temp", 20}})
+    assert.Equal(t, []string{"temp", "rpm"}, p.names)
+    assert.Equal(t, []float64{21, 22, 20}, p.series["temp"])
+    assert.Equal(t, []string{
+        "temp         20  =#_  min 20  max 22",
+        "rpm        1000  _    min 1000  max 1000",
+    }, p.render())
+
+    out := &bytes.Buffer{}
+    screen := &plotScreen{out: out, live: true}
+    screen.draw([]string{"a", "b"})
+    screen.draw([]string{"c", "d"})
+    assert.Equal(t, "\r\x1b[2Ka\n\r\x1b[2Kb\n\x1b[2A\r\x1b[2Kc\n\r\x1b[2Kd\n", out.String())
+}
+
+func TestSeriesExporter(t *testing.T) {
+    dir, err := ioutil.TempDir("", "wio-plot")
+    assert.Nil(t, err)
+    defer os.RemoveAll(dir)
+    path := filepath.Join(dir, "series.csv")
+
+    exporter, err := newSeriesExporter(path)
+    assert.Nil(t, err)
+    now := time.Date(2018, 9, 25, 13, 4, 5, 0, time.UTC)
+    assert.Nil(t, exporter.write(now, []sample{{"temp", 21.5}, {"rpm", 1200}}))
+    assert.Nil(t, exporter.Close())
+
+    data, err := ioutil.ReadFile(path)
+    assert.Nil(t, err)
+    assert.Equal(t, "time,series,value\n2018-09-25T13:04:05Z,temp,21.5\n2018-09-25T13:04:05Z,rpm,1200\n", string(data))
+}
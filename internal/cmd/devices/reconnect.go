@@ -0,0 +1,83 @@
+package devices
+
+import (
+    "errors"
+    "sync"
+    "time"
+
+    "go.bug.st/serial.v1"
+)
+
+// How often a port that went away is tried again
+var reconnectInterval = 500 * time.Millisecond
+
+var errDisconnected = errors.New("device is disconnected")
+
+// Serial port the console writes to, swapped for a new one when the device comes back.
+// Writes fail with errDisconnected while it is gone
+type sharedPort struct {
+    mutex sync.Mutex
+    port  serial.Port
+}
+
+func (p *sharedPort) set(port serial.Port) {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+    p.port = port
+}
+
+// Closes the current port and marks the device as disconnected
+func (p *sharedPort) close() error {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+    if p.port == nil {
+        return nil
+    }
+    err := p.port.Close()
+    p.port = nil
+    return err
+}
+
+func (p *sharedPort) Write(data []byte) (int, error) {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+    if p.port == nil {
+        return 0, errDisconnected
+    }
+    return p.port.Write(data)
+}
+
+func (p *sharedPort) SetDTR(dtr bool) error {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+    if p.port == nil {
+        return errDisconnected
+    }
+    return p.port.SetDTR(dtr)
+}
+
+func (p *sharedPort) SetRTS(rts bool) error {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+    if p.port == nil {
+        return errDisconnected
+    }
+    return p.port.SetRTS(rts)
+}
+
+// Tries to open the port until it works, which is once the device is plugged back in or an
+// upload is done with it. Returns nil if stop is closed first
+func waitForPort(open func() (serial.Port, error), stop <-chan struct{}) serial.Port {
+    ticker := time.NewTicker(reconnectInterval)
+    defer ticker.Stop()
+    for {
+        if port, err := open(); err == nil {
+            return port
+        }
+        select {
+        case <-ticker.C:
+        case <-stop:
+            return nil
+        }
+    }
+}
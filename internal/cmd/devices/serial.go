@@ -0,0 +1,64 @@
+package devices
+
+import (
+    "strings"
+    "wio/pkg/util"
+
+    "go.bug.st/serial.v1"
+)
+
+var parities = map[string]serial.Parity{
+    "none":  serial.NoParity,
+    "odd":   serial.OddParity,
+    "even":  serial.EvenParity,
+    "mark":  serial.MarkParity,
+    "space": serial.SpaceParity,
+}
+
+var stopBits = map[string]serial.StopBits{
+    "1":   serial.OneStopBit,
+    "1.5": serial.OnePointFiveStopBits,
+    "2":   serial.TwoStopBits,
+}
+
+// Serial settings from the baud rate, data bits, parity name and number of stop bits
+func NewMode(baud int, dataBits int, parity string, stop string) (*serial.Mode, error) {
+    if dataBits < 5 || dataBits > 8 {
+        return nil, util.Error("data bits must be between 5 and 8")
+    }
+    parityValue, exists := parities[strings.ToLower(parity)]
+    if !exists {
+        return nil, util.Error("parity [%s] is not supported, use none, odd, even, mark or space", parity)
+    }
+    stopValue, exists := stopBits[stop]
+    if !exists {
+        return nil, util.Error("stop bits must be 1, 1.5 or 2")
+    }
+    return &serial.Mode{
+        BaudRate: baud,
+        Parity:   parityValue,
+        DataBits: dataBits,
+        StopBits: stopValue,
+    }, nil
+}
+
+// Opens the serial port at the given baud rate with 8N1 framing
+func OpenPort(portName string, baud int) (serial.Port, error) {
+    mode, _ := NewMode(baud, 8, "none", "1")
+    return OpenPortMode(portName, mode)
+}
+
+// Opens the serial port with the given settings, or connects to it if it is on the network
+func OpenPortMode(portName string, mode *serial.Mode) (serial.Port, error) {
+    if isNetworkPort(portName) {
+        return openNetworkPort(portName, mode)
+    }
+    port, err := serial.Open(portName, mode)
+    if err != nil {
+        if strings.Contains(err.Error(), "Invalid serial port") {
+            return nil, util.Error("invalid baud rate")
+        }
+        return nil, util.Error("%s port is not valid or cannot be used: %s", portName, err.Error())
+    }
+    return port, nil
+}
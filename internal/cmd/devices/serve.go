@@ -0,0 +1,309 @@
+package devices
+
+import (
+    "encoding/binary"
+    "io"
+    "net"
+    "sync"
+    "wio/pkg/log"
+    "wio/pkg/util"
+
+    "github.com/urfave/cli"
+    "go.bug.st/serial.v1"
+)
+
+const (
+    protocolRaw     = "raw"
+    protocolRfc2217 = "rfc2217"
+)
+
+// Client of a serial port shared over the network
+type serverClient struct {
+    conn    net.Conn
+    mutex   sync.Mutex
+    options *telnetOptions
+}
+
+func (c *serverClient) send(message []byte) error {
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+    _, err := c.conn.Write(message)
+    return err
+}
+
+// Shares a serial port over tcp with one client at a time. A client that connects takes the
+// port over from the previous one, which also gets rid of connections that were dropped
+// without being closed
+type serialServer struct {
+    port    serial.Port
+    rfc2217 bool
+    // guards the client and the mode
+    mutex  sync.Mutex
+    client *serverClient
+    mode   serial.Mode
+}
+
+func newSerialServer(port serial.Port, mode *serial.Mode, protocol string) (*serialServer, error) {
+    if protocol != protocolRaw && protocol != protocolRfc2217 {
+        return nil, util.Error("protocol [%s] is not supported, use raw or rfc2217", protocol)
+    }
+    return &serialServer{port: port, mode: *mode, rfc2217: protocol == protocolRfc2217}, nil
+}
+
+func (s *serialServer) currentClient() *serverClient {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    return s.client
+}
+
+// Sends what the device writes to the client, until the port fails or is closed
+func (s *serialServer) forwardPort() error {
+    buffer := make([]byte, 256)
+    for {
+        n, err := s.port.Read(buffer)
+        if err == nil && n == 0 {
+            err = io.EOF
+        }
+        if err != nil {
+            return err
+        }
+        if client := s.currentClient(); client != nil {
+            data := buffer[:n]
+            if s.rfc2217 {
+                data = escapeTelnet(data)
+            }
+            // a client that went away is dropped once it is read from
+            client.send(data)
+        }
+    }
+}
+
+// Applies a com port command of the client and returns the answer
+func (s *serialServer) comPortCommand(sub []byte) []byte {
+    if len(sub) < 3 || sub[0] != optionComPort {
+        return nil
+    }
+    command, value := sub[1], sub[2:]
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    mode := s.mode
+    var err error
+
+    switch command {
+    case comSetBaud:
+        if len(value) != 4 {
+            return nil
+        }
+        if baud := int(binary.BigEndian.Uint32(value)); baud != 0 {
+            mode.BaudRate = baud
+        }
+        if err = s.setMode(mode); err != nil {
+            log.Warnln("could not set the baud rate to %d: %s", mode.BaudRate, err.Error())
+        }
+        return comBaud(command+serverOffset, s.mode.BaudRate)
+    case comSetDataSize:
+        if value[0] != 0 {
+            mode.DataBits = int(value[0])
+        }
+        if err = s.setMode(mode); err != nil {
+            log.Warnln("could not set the data bits to %d: %s", mode.DataBits, err.Error())
+        }
+        return comCommand(command+serverOffset, byte(s.mode.DataBits))
+    case comSetParity:
+        for parity, code := range comParities {
+            if code == value[0] {
+                mode.Parity = parity
+            }
+        }
+        if err = s.setMode(mode); err != nil {
+            log.Warnln("could not set the parity: %s", err.Error())
+        }
+        return comCommand(command+serverOffset, comParities[s.mode.Parity])
+    case comSetStopSize:
+        for stop, code := range comStopBits {
+            if code == value[0] {
+                mode.StopBits = stop
+            }
+        }
+        if err = s.setMode(mode); err != nil {
+            log.Warnln("could not set the stop bits: %s", err.Error())
+        }
+        return comCommand(command+serverOffset, comStopBits[s.mode.StopBits])
+    case comSetControl:
+        switch value[0] {
+        case controlDtrOn, controlDtrOff:
+            err = s.port.SetDTR(value[0] == controlDtrOn)
+        case controlRtsOn, controlRtsOff:
+            err = s.port.SetRTS(value[0] == controlRtsOn)
+        }
+        if err != nil {
+            log.Warnln("could not set the control lines: %s", err.Error())
+        }
+        return comCommand(command+serverOffset, value[0])
+    case comPurgeData:
+        if value[0] == purgeReceive || value[0] == purgeBoth {
+            err = s.port.ResetInputBuffer()
+        }
+        if err == nil && (value[0] == purgeTransmit || value[0] == purgeBoth) {
+            err = s.port.ResetOutputBuffer()
+        }
+        if err != nil {
+            log.Warnln("could not purge the port: %s", err.Error())
+        }
+        return comCommand(command+serverOffset, value[0])
+    }
+    return nil
+}
+
+// Changes the settings of the port, the mutex is held by the caller
+func (s *serialServer) setMode(mode serial.Mode) error {
+    if mode == s.mode {
+        return nil
+    }
+    if err := s.port.SetMode(&mode); err != nil {
+        return err
+    }
+    s.mode = mode
+    return nil
+}
+
+// Writes what the client sends to the port until it disconnects or is replaced
+func (s *serialServer) handleClient(client *serverClient) {
+    defer func() {
+        s.mutex.Lock()
+        if s.client == client {
+            s.client = nil
+        }
+        s.mutex.Unlock()
+        client.conn.Close()
+        log.Infoln(log.Yellow, "--- %s disconnected ---", client.conn.RemoteAddr())
+    }()
+
+    decoder := telnetDecoder{}
+    buffer := make([]byte, 256)
+    for {
+        n, err := client.conn.Read(buffer)
+        data := buffer[:n]
+        if s.rfc2217 {
+            var answers []byte
+            data = decoder.decode(data, func(verb byte, option byte) {
+                answers = append(answers, client.options.answer(verb, option)...)
+            }, func(sub []byte) {
+                answers = append(answers, s.comPortCommand(sub)...)
+            })
+            if len(answers) > 0 {
+                client.send(answers)
+            }
+        }
+        if len(data) > 0 {
+            if _, err := s.port.Write(data); err != nil {
+                log.Errln("failed to write to the port: %s", err.Error())
+                return
+            }
+        }
+        if err != nil {
+            return
+        }
+    }
+}
+
+// Accepts clients until the listener is closed
+func (s *serialServer) serve(listener net.Listener) error {
+    for {
+        conn, err := listener.Accept()
+        if err != nil {
+            return err
+        }
+        client := &serverClient{conn: conn}
+        if s.rfc2217 {
+            client.options = newTelnetOptions([]byte{optionBinary, optionSGA}, []byte{optionBinary, optionComPort})
+        }
+        s.mutex.Lock()
+        previous := s.client
+        s.client = client
+        s.mutex.Unlock()
+        if previous != nil {
+            log.Infoln(log.Yellow, "--- %s takes over from %s ---", conn.RemoteAddr(), previous.conn.RemoteAddr())
+            previous.conn.Close()
+        } else {
+            log.Infoln(log.Green, "--- %s connected ---", conn.RemoteAddr())
+        }
+        go s.handleClient(client)
+    }
+}
+
+// Shares the port until stop is closed or the port fails
+func (s *serialServer) run(listener net.Listener, stop <-chan struct{}) error {
+    portErr := make(chan error, 1)
+    go func() {
+        portErr <- s.forwardPort()
+        listener.Close()
+    }()
+    go func() {
+        <-stop
+        listener.Close()
+    }()
+
+    s.serve(listener)
+    s.mutex.Lock()
+    if s.client != nil {
+        s.client.conn.Close()
+    }
+    s.mutex.Unlock()
+    select {
+    case <-stop:
+        return nil
+    case err := <-portErr:
+        return err
+    }
+}
+
+// Shares a serial port over the network
+func handleServe(context *cli.Context) error {
+    portName := ""
+    if context.IsSet("port") {
+        portName = context.String("port")
+    }
+    portName, err := findPort(portName)
+    if err != nil {
+        return err
+    }
+    if isNetworkPort(portName) {
+        return util.Error("%s is already on the network", portName)
+    }
+    mode, err := NewMode(context.Int("baud"), 8, "none", "1")
+    if err != nil {
+        return err
+    }
+    // closing the port stops the reader that forwards it to the client
+    serialPort, err := OpenInterruptiblePortMode(portName, mode)
+    if err != nil {
+        return err
+    }
+    defer serialPort.Close()
+    server, err := newSerialServer(serialPort, mode, context.String("protocol"))
+    if err != nil {
+        return err
+    }
+    listener, err := net.Listen("tcp", context.String("listen"))
+    if err != nil {
+        return err
+    }
+
+    scheme := tcpScheme
+    if server.rfc2217 {
+        scheme = rfc2217Scheme
+    }
+    log.Info(log.Cyan, "Serving ")
+    log.Info(log.Magenta, portName)
+    log.Info(log.Cyan, " on ")
+    log.Infoln(log.Magenta, scheme+listener.Addr().String())
+    log.Infoln(log.Cyan, "--- Quit: Ctrl+C ---")
+
+    stop, release := stopChannel(nil)
+    defer release()
+    if err := server.run(listener, stop); err != nil && err != io.EOF {
+        return util.Error("%s port is not valid or cannot be used: %s", portName, err.Error())
+    }
+    return nil
+}
@@ -0,0 +1,102 @@
+package devices
+
+import (
+    "fmt"
+    "io"
+    "net"
+    "os"
+    "syscall"
+    "testing"
+    "time"
+    "unsafe"
+
+    "github.com/stretchr/testify/assert"
+)
+
+// Opens a pseudo-terminal pair. The slave end stands in for the board's serial port
+func openPty(t *testing.T) (*os.File, string) {
+    master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+    if err != nil {
+        t.Skip("pseudo-terminals are not available")
+    }
+    unlock := int32(0)
+    if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCSPTLCK,
+        uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+        t.Fatal(errno)
+    }
+    number := uint32(0)
+    if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCGPTN,
+        uintptr(unsafe.Pointer(&number))); errno != 0 {
+        t.Fatal(errno)
+    }
+    return master, fmt.Sprintf("/dev/pts/%d", number)
+}
+
+func readFull(t *testing.T, reader io.Reader, size int) string {
+    data := make([]byte, size)
+    _, err := io.ReadFull(reader, data)
+    assert.Nil(t, err)
+    return string(data)
+}
+
+// Serves the slave end of a pseudo-terminal and connects to it with the given scheme
+func testServe(t *testing.T, protocol string, scheme string) {
+    master, slave := openPty(t)
+    defer master.Close()
+    mode, _ := NewMode(9600, 8, "none", "1")
+    serialPort, err := OpenInterruptiblePortMode(slave, mode)
+    assert.Nil(t, err)
+    defer serialPort.Close()
+
+    server, err := newSerialServer(serialPort, mode, protocol)
+    assert.Nil(t, err)
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    assert.Nil(t, err)
+    stop := make(chan struct{})
+    done := make(chan error)
+    go func() {
+        done <- server.run(listener, stop)
+    }()
+
+    client, err := OpenPortMode(scheme+listener.Addr().String(), mode)
+    assert.Nil(t, err)
+    defer client.Close()
+
+    // binary data goes through both ways, including the telnet escape byte
+    _, err = client.Write([]byte("led on\xff\x00\n"))
+    assert.Nil(t, err)
+    assert.Equal(t, "led on\xff\x00\n", readFull(t, master, 9))
+
+    for server.currentClient() == nil {
+        time.Sleep(time.Millisecond)
+    }
+    master.Write([]byte("ok \xff\x01\n"))
+    assert.Equal(t, "ok \xff\x01\n", readFull(t, client, 6))
+
+    if protocol == protocolRfc2217 {
+        mode, _ := NewMode(115200, 7, "even", "2")
+        assert.Nil(t, client.SetMode(mode))
+        deadline := time.Now().Add(5 * time.Second)
+        for time.Now().Before(deadline) {
+            server.mutex.Lock()
+            current := server.mode
+            server.mutex.Unlock()
+            if current == *mode {
+                break
+            }
+            time.Sleep(10 * time.Millisecond)
+        }
+        assert.Equal(t, *mode, server.mode)
+    }
+
+    close(stop)
+    assert.Nil(t, <-done)
+}
+
+func TestServeRfc2217(t *testing.T) {
+    testServe(t, protocolRfc2217, rfc2217Scheme)
+}
+
+func TestServeRaw(t *testing.T) {
+    testServe(t, protocolRaw, tcpScheme)
+}
@@ -0,0 +1,219 @@
+package devices
+
+import (
+    "bytes"
+    "encoding/binary"
+
+    "go.bug.st/serial.v1"
+)
+
+// Telnet commands and options used by RFC 2217, which controls a serial port over telnet
+const (
+    telnetSE   = 240
+    telnetSB   = 250
+    telnetWILL = 251
+    telnetWONT = 252
+    telnetDO   = 253
+    telnetDONT = 254
+    telnetIAC  = 255
+
+    optionBinary  = 0
+    optionSGA     = 3
+    optionComPort = 44
+)
+
+// Com port commands sent by the client, the server answers with the command plus serverOffset
+const (
+    comSetBaud     = 1
+    comSetDataSize = 2
+    comSetParity   = 3
+    comSetStopSize = 4
+    comSetControl  = 5
+    comPurgeData   = 12
+    serverOffset   = 100
+
+    controlDtrOn  = 8
+    controlDtrOff = 9
+    controlRtsOn  = 11
+    controlRtsOff = 12
+
+    purgeReceive  = 1
+    purgeTransmit = 2
+    purgeBoth     = 3
+)
+
+// Values of SET-PARITY and SET-STOPSIZE
+var (
+    comParities = map[serial.Parity]byte{
+        serial.NoParity:    1,
+        serial.OddParity:   2,
+        serial.EvenParity:  3,
+        serial.MarkParity:  4,
+        serial.SpaceParity: 5,
+    }
+    comStopBits = map[serial.StopBits]byte{
+        serial.OneStopBit:           1,
+        serial.TwoStopBits:          2,
+        serial.OnePointFiveStopBits: 3,
+    }
+)
+
+const (
+    stateData = iota
+    stateIAC
+    stateOption
+    stateSub
+    stateSubIAC
+)
+
+// Separates the data received over telnet from the commands in it
+type telnetDecoder struct {
+    state int
+    verb  byte
+    sub   []byte
+}
+
+// Returns the data in what was received. option is called for WILL, WONT, DO and DONT
+// and sub with the content of each subnegotiation
+func (d *telnetDecoder) decode(in []byte, option func(verb byte, option byte), sub func([]byte)) []byte {
+    data := make([]byte, 0, len(in))
+    for _, b := range in {
+        switch d.state {
+        case stateData:
+            if b == telnetIAC {
+                d.state = stateIAC
+            } else {
+                data = append(data, b)
+            }
+        case stateIAC:
+            switch b {
+            case telnetIAC:
+                data = append(data, b)
+                d.state = stateData
+            case telnetWILL, telnetWONT, telnetDO, telnetDONT:
+                d.verb = b
+                d.state = stateOption
+            case telnetSB:
+                d.sub = d.sub[:0]
+                d.state = stateSub
+            default:
+                // other commands, like NOP, carry nothing
+                d.state = stateData
+            }
+        case stateOption:
+            option(d.verb, b)
+            d.state = stateData
+        case stateSub:
+            if b == telnetIAC {
+                d.state = stateSubIAC
+            } else {
+                d.sub = append(d.sub, b)
+            }
+        case stateSubIAC:
+            switch b {
+            case telnetSE:
+                sub(d.sub)
+                d.state = stateData
+            case telnetIAC:
+                d.sub = append(d.sub, b)
+                d.state = stateSub
+            default:
+                d.state = stateSub
+            }
+        }
+    }
+    return data
+}
+
+// Doubles the IAC bytes in data so they are not read as commands
+func escapeTelnet(data []byte) []byte {
+    return bytes.Replace(data, []byte{telnetIAC}, []byte{telnetIAC, telnetIAC}, -1)
+}
+
+// Com port command with its value
+func comCommand(command byte, value ...byte) []byte {
+    message := []byte{telnetIAC, telnetSB, optionComPort, command}
+    message = append(message, escapeTelnet(value)...)
+    return append(message, telnetIAC, telnetSE)
+}
+
+func comBaud(command byte, baud int) []byte {
+    value := make([]byte, 4)
+    binary.BigEndian.PutUint32(value, uint32(baud))
+    return comCommand(command, value...)
+}
+
+// Commands setting the port to the mode
+func comMode(mode *serial.Mode) []byte {
+    message := comBaud(comSetBaud, mode.BaudRate)
+    message = append(message, comCommand(comSetDataSize, byte(mode.DataBits))...)
+    message = append(message, comCommand(comSetParity, comParities[mode.Parity])...)
+    return append(message, comCommand(comSetStopSize, comStopBits[mode.StopBits])...)
+}
+
+// Options each side has agreed to, so that an option is only answered once and the two sides
+// do not keep acknowledging each other
+type telnetOptions struct {
+    // options this side may enable, and the ones the other side may
+    supportedLocal  map[byte]bool
+    supportedRemote map[byte]bool
+    local           map[byte]bool
+    remote          map[byte]bool
+}
+
+func newTelnetOptions(supportedLocal []byte, supportedRemote []byte) *telnetOptions {
+    o := &telnetOptions{
+        supportedLocal:  map[byte]bool{},
+        supportedRemote: map[byte]bool{},
+        local:           map[byte]bool{},
+        remote:          map[byte]bool{},
+    }
+    for _, option := range supportedLocal {
+        o.supportedLocal[option] = true
+    }
+    for _, option := range supportedRemote {
+        o.supportedRemote[option] = true
+    }
+    return o
+}
+
+// Asks for the options to be enabled on both sides
+func (o *telnetOptions) request(local []byte, remote []byte) []byte {
+    var message []byte
+    for _, option := range local {
+        o.local[option] = true
+        message = append(message, telnetIAC, telnetWILL, option)
+    }
+    for _, option := range remote {
+        o.remote[option] = true
+        message = append(message, telnetIAC, telnetDO, option)
+    }
+    return message
+}
+
+// Answer to a WILL, WONT, DO or DONT, nil if none is needed
+func (o *telnetOptions) answer(verb byte, option byte) []byte {
+    switch verb {
+    case telnetDO:
+        if !o.supportedLocal[option] {
+            return []byte{telnetIAC, telnetWONT, option}
+        }
+        if !o.local[option] {
+            o.local[option] = true
+            return []byte{telnetIAC, telnetWILL, option}
+        }
+    case telnetWILL:
+        if !o.supportedRemote[option] {
+            return []byte{telnetIAC, telnetDONT, option}
+        }
+        if !o.remote[option] {
+            o.remote[option] = true
+            return []byte{telnetIAC, telnetDO, option}
+        }
+    case telnetDONT:
+        delete(o.local, option)
+    case telnetWONT:
+        delete(o.remote, option)
+    }
+    return nil
+}
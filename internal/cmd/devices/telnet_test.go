@@ -0,0 +1,56 @@
+package devices
+
+import (
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "go.bug.st/serial.v1"
+)
+
+func TestTelnetDecoder(t *testing.T) {
+    decoder := telnetDecoder{}
+    var options [][2]byte
+    var subs [][]byte
+    option := func(verb byte, option byte) {
+        options = append(options, [2]byte{verb, option})
+    }
+    sub := func(data []byte) {
+        subs = append(subs, append([]byte(nil), data...))
+    }
+
+    data := decoder.decode([]byte("ab\xff\xffc\xff\xfb\x2c\xff\xfa\x2c\x65\x00\x00"), option, sub)
+    assert.Equal(t, []byte("ab\xffc"), data)
+    // the subnegotiation ends in the next chunk
+    data = decoder.decode([]byte("\xff\xff\x00\xff\xf0d\xff\xf1e"), option, sub)
+    assert.Equal(t, []byte("de"), data)
+    assert.Equal(t, [][2]byte{{telnetWILL, optionComPort}}, options)
+    assert.Equal(t, [][]byte{{optionComPort, 0x65, 0, 0, 0xff, 0}}, subs)
+
+    assert.Equal(t, []byte("a\xff\xffb"), escapeTelnet([]byte("a\xffb")))
+}
+
+func TestComMode(t *testing.T) {
+    mode := &serial.Mode{BaudRate: 115200, DataBits: 7, Parity: serial.EvenParity, StopBits: serial.TwoStopBits}
+    assert.Equal(t, []byte("\xff\xfa\x2c\x01\x00\x01\xc2\x00\xff\xf0"+
+        "\xff\xfa\x2c\x02\x07\xff\xf0"+
+        "\xff\xfa\x2c\x03\x03\xff\xf0"+
+        "\xff\xfa\x2c\x04\x02\xff\xf0"), comMode(mode))
+}
+
+func TestTelnetOptions(t *testing.T) {
+    client := newTelnetOptions([]byte{optionBinary, optionComPort}, []byte{optionBinary})
+    server := newTelnetOptions([]byte{optionBinary}, []byte{optionBinary, optionComPort})
+
+    request := client.request([]byte{optionComPort}, []byte{optionBinary})
+    assert.Equal(t, []byte{telnetIAC, telnetWILL, optionComPort, telnetIAC, telnetDO, optionBinary}, request)
+
+    assert.Equal(t, []byte{telnetIAC, telnetDO, optionComPort}, server.answer(telnetWILL, optionComPort))
+    assert.Equal(t, []byte{telnetIAC, telnetWILL, optionBinary}, server.answer(telnetDO, optionBinary))
+    // acknowledgements of what was asked for are not answered again
+    assert.Nil(t, client.answer(telnetDO, optionComPort))
+    assert.Nil(t, client.answer(telnetWILL, optionBinary))
+    assert.Nil(t, server.answer(telnetWILL, optionComPort))
+
+    assert.Equal(t, []byte{telnetIAC, telnetWONT, 1}, server.answer(telnetDO, 1))
+    assert.Equal(t, []byte{telnetIAC, telnetDONT, optionSGA}, client.answer(telnetWILL, optionSGA))
+}
@@ -0,0 +1,165 @@
+package devices
+
+import (
+    "encoding/csv"
+    "encoding/json"
+    "io"
+    "os"
+    "time"
+    "wio/internal/toolchain"
+    "wio/pkg/log"
+    "wio/pkg/util"
+
+    "github.com/urfave/cli"
+)
+
+const (
+    eventAdd    = "add"
+    eventRemove = "remove"
+)
+
+// Port that appeared or disappeared, written as one json object per line in the json format
+type portEvent struct {
+    Event string    `json:"event"`
+    Time  time.Time `json:"time"`
+    portInfo
+    port toolchain.SerialPort
+}
+
+func newPortEvent(event string, port toolchain.SerialPort, now time.Time) portEvent {
+    return portEvent{Event: event, Time: now, portInfo: newPortInfo(port), port: port}
+}
+
+// A device that re-enumerates on the same port, e.g. after being reset, has a new identity
+func portKey(port toolchain.SerialPort) string {
+    return port.Port + "|" + port.Vid + "|" + port.Pid + "|" + port.SerialNumber
+}
+
+// Ports that were added and then the ones that were removed between two listings
+func diffPorts(old []toolchain.SerialPort, current []toolchain.SerialPort, now time.Time) []portEvent {
+    oldKeys := map[string]bool{}
+    for _, port := range old {
+        oldKeys[portKey(port)] = true
+    }
+    currentKeys := map[string]bool{}
+    for _, port := range current {
+        currentKeys[portKey(port)] = true
+    }
+
+    var events []portEvent
+    for _, port := range current {
+        if !oldKeys[portKey(port)] {
+            events = append(events, newPortEvent(eventAdd, port, now))
+        }
+    }
+    for _, port := range old {
+        if !currentKeys[portKey(port)] {
+            events = append(events, newPortEvent(eventRemove, port, now))
+        }
+    }
+    return events
+}
+
+type eventWriter struct {
+    format string
+    out    io.Writer
+    csv    *csv.Writer
+}
+
+func newEventWriter(format string, out io.Writer) (*eventWriter, error) {
+    writer := &eventWriter{format: format, out: out}
+    if format == formatCsv {
+        writer.csv = csv.NewWriter(out)
+        header := append([]string{"event", "time"}, portInfoHeader...)
+        if err := writer.csv.Write(header); err != nil {
+            return nil, err
+        }
+        writer.csv.Flush()
+    }
+    return writer, nil
+}
+
+// Writes an event right away so that scripts reading the output see it as it happens
+func (w *eventWriter) write(event portEvent) error {
+    switch w.format {
+    case formatJson:
+        data, err := json.Marshal(event)
+        if err != nil {
+            return err
+        }
+        _, err = w.out.Write(append(data, '\n'))
+        return err
+    case formatCsv:
+        record := append([]string{event.Event, event.Time.Format(time.RFC3339Nano)}, event.record()...)
+        if err := w.csv.Write(record); err != nil {
+            return err
+        }
+        w.csv.Flush()
+        return w.csv.Error()
+    }
+    color := log.Green
+    sign := "+"
+    if event.Event == eventRemove {
+        color = log.Red
+        sign = "-"
+    }
+    log.Info(color, "%s %s ", sign, event.Port)
+    log.Infoln("%s", describeBoard(event.port))
+    return nil
+}
+
+// Prints ports as they appear and disappear until interrupted. With --until the watch ends
+// at the first event of that kind, on the port given with --port if there is one
+func handleWatch(context *cli.Context) error {
+    format := context.String("format")
+    if err := checkFormat(format); err != nil {
+        return err
+    }
+    until := context.String("until")
+    if until != "" && until != eventAdd && until != eventRemove {
+        return util.Error("--until must be either %s or %s", eventAdd, eventRemove)
+    }
+    interval := context.Duration("interval")
+    if interval <= 0 {
+        return util.Error("--interval must be positive")
+    }
+    timeout := context.Duration("timeout")
+    portFilter := context.String("port")
+
+    writer, err := newEventWriter(format, os.Stdout)
+    if err != nil {
+        return err
+    }
+    ports, err := toolchain.GetPorts()
+    if err != nil {
+        return err
+    }
+    if format == formatText {
+        log.Infoln(log.Cyan, "--- Watching serial ports, Quit: Ctrl+C ---")
+    }
+
+    start := time.Now()
+    for timeout <= 0 || time.Since(start) < timeout {
+        time.Sleep(interval)
+        current, err := toolchain.GetPorts()
+        if err != nil {
+            return err
+        }
+        for _, event := range diffPorts(ports.Ports, current.Ports, time.Now()) {
+            if portFilter != "" && event.Port != portFilter {
+                continue
+            }
+            if err := writer.write(event); err != nil {
+                return err
+            }
+            if event.Event == until {
+                return nil
+            }
+        }
+        ports = current
+    }
+    if until != "" {
+        return util.Error("timed out after %s waiting for a port %s event", timeout, until)
+    }
+    return nil
+}
@@ -2,44 +2,133 @@ package run
 
 import (
     "strings"
+    "wio/internal/constants"
     "wio/internal/toolchain"
     "wio/internal/types"
+    "wio/pkg/log"
     "wio/pkg/util"
-    "wio/pkg/util/sys"
 )
 
-func getPort(info *runInfo) (string, error) {
-    if info.context.IsSet("port") {
-        return info.context.String("port"), nil
+func isBaremetal(target types.Target) bool {
+    return strings.EqualFold(strings.TrimSpace(target.GetFramework()), constants.Baremetal)
+}
+
+// Board of an AVR target. Bare-metal targets may set the mcu and f_cpu in wio.yml, which take
+// priority over the ones of the board, or name a chip that is not a known board if they set both
+func avrBoard(target types.Target) (*toolchain.Board, error) {
+    board, err := toolchain.GetAvrBoard(target.GetBoard())
+    if !isBaremetal(target) {
+        return board, err
     }
-    ports, err := toolchain.GetPorts()
+    mcu, fCpu := strings.ToLower(strings.TrimSpace(target.GetMcu())), target.GetFCpu()
     if err != nil {
-        return "", err
+        if mcu == "" || fCpu <= 0 {
+            return nil, util.Error("board [%s] is not known, set the mcu and f_cpu of target %s",
+                target.GetBoard(), target.GetName())
+        }
+        return &toolchain.Board{Name: target.GetBoard(), Mcu: mcu, FCpu: fCpu}, nil
     }
-    serialPort := toolchain.GetArduinoPort(ports)
-    if serialPort == nil {
-        return "", util.Error("failed to find Arduino port")
+
+    custom := *board
+    if mcu != "" && mcu != board.Mcu {
+        // the memory sizes of the board are not the ones of another chip
+        custom.Mcu, custom.Flash, custom.Ram = mcu, 0, 0
     }
-    return serialPort.Port, nil
+    if fCpu > 0 {
+        custom.FCpu = fCpu
+    }
+    return &custom, nil
 }
 
-func portReconfigure(info *runInfo, target types.Target) error {
-    // Run check means that executable exists and target is configured
-    port, err := getPort(info)
+// Splits a list of ports separated by commas, dropping empty entries and duplicates
+func splitPorts(value string) []string {
+    var ports []string
+    seen := map[string]bool{}
+    for _, port := range strings.Split(value, ",") {
+        if port = strings.TrimSpace(port); port != "" && !seen[port] {
+            seen[port] = true
+            ports = append(ports, port)
+        }
+    }
+    return ports
+}
+
+// Warns if the device on a port given with --port is known to be another board
+func checkPortBoard(port string, board string) {
+    ports, err := toolchain.GetPorts()
     if err != nil {
-        return err
+        return
+    }
+    for _, serialPort := range ports.Ports {
+        if serialPort.Port == port && toolchain.MatchBoard(serialPort, board) == toolchain.OtherBoard {
+            log.Warnln("%s looks like %s, not %s", port, describeDevice(serialPort), board)
+        }
+    }
+}
+
+// Known boards or the usb to serial adapter of the device on a port
+func describeDevice(port toolchain.SerialPort) string {
+    if boards := toolchain.DetectBoards(port); len(boards) > 0 {
+        return strings.Join(boards, " or ")
     }
-    targetDir := targetPath(info, target)
-    data, err := sys.NormalIO.ReadFile(sys.Path(targetDir, "CMakeLists.txt"))
+    if bridge := toolchain.SerialBridge(port); bridge != "" {
+        return "a " + bridge + " usb serial adapter"
+    }
+    return "an unknown device"
+}
+
+// Ports given with --port or else the configured ones from wio.yml, separated by commas.
+// Without either it is the one the device fitting the board best is connected to. An empty
+// board accepts any Arduino
+func getPorts(info *runInfo, board string, configured string) ([]string, error) {
+    if info.context.IsSet("port") {
+        configured = info.context.String("port")
+    }
+    if configured != "" {
+        ports := splitPorts(configured)
+        if len(ports) == 0 {
+            return nil, util.Error("no port given")
+        }
+        if board != "" {
+            for _, port := range ports {
+                checkPortBoard(port, board)
+            }
+        }
+        return ports, nil
+    }
+    ports, err := toolchain.GetPorts()
     if err != nil {
-        return err
+        return nil, err
     }
-    if !strings.Contains(string(data), port) {
-        _, err := configureTargets(info, []types.Target{target})
-        if err != nil {
-            return err
+    if board == "" {
+        serialPort := toolchain.GetArduinoPort(ports)
+        if serialPort == nil {
+            return nil, util.Error("failed to find Arduino port")
         }
-        return configTarget(binaryPath(info, target))
+        return []string{serialPort.Port}, nil
+    }
+
+    serialPort, match := toolchain.GetBoardPort(ports, board)
+    if serialPort == nil {
+        return nil, util.Error("failed to find a port for board %s", board)
+    }
+    switch match {
+    case toolchain.OtherBoard:
+        log.Warnln("no %s found, using %s which looks like %s", board, serialPort.Port, describeDevice(*serialPort))
+    case toolchain.BridgeDevice:
+        log.Verbln("using %s, %s that may be connected to a %s", serialPort.Port, describeDevice(*serialPort), board)
+    }
+    return []string{serialPort.Port}, nil
+}
+
+// Single port for commands that talk to one device
+func getPort(info *runInfo, board string, configured string) (string, error) {
+    ports, err := getPorts(info, board, configured)
+    if err != nil {
+        return "", err
+    }
+    if len(ports) > 1 {
+        return "", util.Error("only one port can be used here, got %s", strings.Join(ports, ", "))
     }
-    return nil
+    return ports[0], nil
 }
@@ -0,0 +1,65 @@
+package run
+
+import (
+    "flag"
+    "testing"
+    "wio/internal/types"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/urfave/cli"
+)
+
+func TestSplitPorts(t *testing.T) {
+    assert.Equal(t, []string{"/dev/ttyACM0"}, splitPorts("/dev/ttyACM0"))
+    assert.Equal(t, []string{"/dev/ttyACM0", "/dev/ttyUSB0", "COM3"},
+        splitPorts(" /dev/ttyACM0,/dev/ttyUSB0, ,COM3,/dev/ttyACM0"))
+    assert.Equal(t, 0, len(splitPorts(" , ")))
+}
+
+func TestAvrBoard(t *testing.T) {
+    board, err := avrBoard(&types.TargetImpl{Framework: "arduino", Board: "uno", Mcu: "atmega8", FCpu: 8000000})
+    assert.Nil(t, err)
+    assert.Equal(t, "atmega328p", board.Mcu)
+    assert.Equal(t, 16000000, board.FCpu)
+
+    board, err = avrBoard(&types.TargetImpl{Framework: "baremetal", Board: "uno", FCpu: 8000000})
+    assert.Nil(t, err)
+    assert.Equal(t, "atmega328p", board.Mcu)
+    assert.Equal(t, 8000000, board.FCpu)
+    assert.Equal(t, int64(32256), board.Flash)
+
+    board, err = avrBoard(&types.TargetImpl{Framework: "baremetal", Board: "uno", Mcu: "ATmega328PB"})
+    assert.Nil(t, err)
+    assert.Equal(t, "atmega328pb", board.Mcu)
+    assert.Equal(t, int64(0), board.Flash)
+
+    board, err = avrBoard(&types.TargetImpl{Framework: "baremetal", Board: "sensor", Mcu: "attiny44", FCpu: 1000000})
+    assert.Nil(t, err)
+    assert.Equal(t, "attiny44", board.Mcu)
+    assert.Equal(t, 1000000, board.FCpu)
+
+    _, err = avrBoard(&types.TargetImpl{Framework: "baremetal", Board: "sensor", Mcu: "attiny44"})
+    assert.NotNil(t, err)
+    _, err = avrBoard(&types.TargetImpl{Framework: "arduino", Board: "sensor", Mcu: "attiny44", FCpu: 1000000})
+    assert.NotNil(t, err)
+
+    // the board of the toolchain is not changed
+    uno, _ := avrBoard(&types.TargetImpl{Framework: "cosa", Board: "uno"})
+    assert.Equal(t, "atmega328p", uno.Mcu)
+    assert.Equal(t, 16000000, uno.FCpu)
+}
+
+func TestUsesUploadRule(t *testing.T) {
+    set := flag.NewFlagSet("upload", flag.ContinueOnError)
+    set.String("programmer", "", "")
+    info := &runInfo{context: cli.NewContext(nil, set, nil)}
+
+    assert.False(t, usesUploadRule(info, &types.TargetImpl{Framework: "arduino", Board: "uno"}))
+    assert.True(t, usesUploadRule(info, &types.TargetImpl{Framework: "arduino", Board: "yun"}))
+    assert.True(t, usesUploadRule(info, &types.TargetImpl{Framework: "cosa", Board: "yun"}))
+    assert.False(t, usesUploadRule(info, &types.TargetImpl{Framework: "baremetal", Board: "yun"}))
+    assert.False(t, usesUploadRule(info, &types.TargetImpl{Framework: "arduino", Board: "yun",
+        Upload: &types.UploadImpl{Programmer: "usbasp"}}))
+
+    assert.Equal(t, []string{"upload", "WIO_UPLOAD_PORT=/dev/ttyACM0"}, uploadTargetArgs("/dev/ttyACM0"))
+}
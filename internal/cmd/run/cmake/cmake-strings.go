@@ -75,6 +75,57 @@ target_include_directories(
     "{{DEPENDENCY_PATH}}/src")
 `
 
+// This is for AVR dependencies built with avr-libc alone, which are compiled with the
+// mcu and clock options of the target
+const AvrBaremetalLibrary = `
+file(GLOB_RECURSE
+    {{DEPENDENCY_NAME}}_files
+    "{{DEPENDENCY_PATH}}/src/*.cpp"
+    "{{DEPENDENCY_PATH}}/src/*.cc"
+    "{{DEPENDENCY_PATH}}/src/*.c"
+    "{{DEPENDENCY_PATH}}/src/*.S")
+
+add_library(
+    {{DEPENDENCY_NAME}}
+    STATIC
+    ${{{DEPENDENCY_NAME}}_files})
+
+set_property(TARGET {{DEPENDENCY_NAME}} PROPERTY CXX_STANDARD {{CXX_STANDARD}})
+set_property(TARGET {{DEPENDENCY_NAME}} PROPERTY C_STANDARD {{C_STANDARD}})
+
+target_compile_definitions(
+    {{DEPENDENCY_NAME}}
+    PRIVATE
+    {{PRIVATE_DEFINITIONS}})
+
+target_compile_definitions(
+    {{DEPENDENCY_NAME}}
+    PUBLIC
+    {{PUBLIC_DEFINITIONS}})
+
+target_compile_definitions(
+    {{DEPENDENCY_NAME}}
+    PRIVATE
+    WIO_PLATFORM_${PLATFORM}
+    WIO_FRAMEWORK_${FRAMEWORK}
+    WIO_BOARD_${BOARD})
+
+target_compile_options(
+    {{DEPENDENCY_NAME}}
+    PUBLIC
+    {{DEPENDENCY_FLAGS}})
+
+target_include_directories(
+    {{DEPENDENCY_NAME}}
+    PUBLIC
+    "{{DEPENDENCY_PATH}}/include")
+
+target_include_directories(
+    {{DEPENDENCY_NAME}}
+    PRIVATE
+    "{{DEPENDENCY_PATH}}/src")
+`
+
 // This for header only desktop dependency
 const DesktopHeader = `
 add_library({{DEPENDENCY_NAME}} INTERFACE)
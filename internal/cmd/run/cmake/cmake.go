@@ -3,6 +3,7 @@ package cmake
 import (
     "os"
     "path/filepath"
+    "strconv"
     "strings"
     "wio/internal/constants"
     "wio/internal/types"
@@ -11,6 +12,10 @@ import (
     "wio/pkg/util/template"
 )
 
+// Make variable the upload rule of arduino and cosa targets reads the port from. The port is
+// given when uploading, so the build does not depend on the device that is connected
+const UploadPortVariable = "WIO_UPLOAD_PORT"
+
 var cppStandards = map[string]string{
     "c++98": "98",
     "c++03": "98",
@@ -78,34 +83,61 @@ func generateCmakeLists(templateFile string, buildPath string, values map[string
     return template.IOReplace(cmakeListsPath, values)
 }
 
-// This creates the main CMakeLists.txt file for AVR app type project
-func GenerateAvrCmakeLists(
+// Values of the build profile, which applies to the target and all of its dependencies
+func profileValues(profile types.Profile) map[string]string {
+    values := map[string]string{
+        "BUILD_TYPE":           "",
+        "PROFILE_FLAGS":        "",
+        "PROFILE_DEFINITIONS":  "",
+        "PROFILE_OPTIMIZATION": "",
+    }
+    if profile == nil {
+        return values
+    }
+    definitions := make([]string, 0, len(profile.GetDefinitions()))
+    for _, definition := range profile.GetDefinitions() {
+        if !strings.HasPrefix(definition, "-D") {
+            definition = "-D" + definition
+        }
+        definitions = append(definitions, definition)
+    }
+    values["BUILD_TYPE"] = profile.GetBuildType()
+    values["PROFILE_FLAGS"] = strings.Join(profile.GetFlags(), " ")
+    values["PROFILE_DEFINITIONS"] = strings.Join(definitions, " ")
+    for _, flag := range profile.GetFlags() {
+        if strings.HasPrefix(flag, "-O") {
+            values["PROFILE_OPTIMIZATION"] = flag
+        }
+    }
+    return values
+}
+
+// Values shared by the CMakeLists.txt templates of AVR targets
+func avrValues(
     toolchainPath string,
     target types.Target,
+    profile types.Profile,
     projectName string,
     projectPath string,
     cppStandard string,
-    cStandard string,
-    port string) error {
+    cStandard string) (map[string]string, error) {
 
     flags := target.GetFlags().GetTarget()
     definitions := target.GetDefinitions().GetTarget()
     framework := target.GetFramework()
-    buildPath := sys.Path(BuildPath(projectPath), target.GetName())
-    templateFile := "CMakeListsAVR"
     executablePath, err := sys.NormalIO.GetRoot()
     if err != nil {
-        return err
+        return nil, err
     }
 
-    return generateCmakeLists(templateFile, buildPath, map[string]string{
+    values := profileValues(profile)
+    for key, value := range map[string]string{
         "TOOLCHAIN_PATH":             filepath.ToSlash(executablePath),
         "TOOLCHAIN_FILE_REL":         filepath.ToSlash(toolchainPath),
         "PROJECT_PATH":               filepath.ToSlash(projectPath),
         "PROJECT_NAME":               projectName,
         "CPP_STANDARD":               cppStandard,
         "C_STANDARD":                 cStandard,
-        "PORT":                       port,
         "PLATFORM":                   strings.ToUpper(constants.Avr),
         "FRAMEWORK":                  strings.ToUpper(framework),
         "BOARD":                      target.GetBoard(),
@@ -113,22 +145,73 @@ func GenerateAvrCmakeLists(
         "ENTRY":                      target.GetSource(),
         "TARGET_COMPILE_FLAGS":       strings.Join(flags, " "),
         "TARGET_COMPILE_DEFINITIONS": strings.Join(definitions, " "),
-    })
+    } {
+        values[key] = value
+    }
+    return values, nil
 }
 
-func GenerateNativeCmakeLists(
+// This creates the main CMakeLists.txt file for AVR app type project
+func GenerateAvrCmakeLists(
+    toolchainPath string,
     target types.Target,
+    buildPath string,
+    profile types.Profile,
     projectName string,
     projectPath string,
     cppStandard string,
     cStandard string) error {
 
+    values, err := avrValues(toolchainPath, target, profile, projectName, projectPath, cppStandard, cStandard)
+    if err != nil {
+        return err
+    }
+    values["UPLOAD_PORT"] = "$(" + UploadPortVariable + ")"
+    return generateCmakeLists("CMakeListsAVR", buildPath, values)
+}
+
+// This creates the main CMakeLists.txt file for AVR targets built with avr-libc alone,
+// which are compiled for the given mcu and clock
+func GenerateAvrBaremetalCmakeLists(
+    target types.Target,
+    buildPath string,
+    profile types.Profile,
+    projectName string,
+    projectPath string,
+    cppStandard string,
+    cStandard string,
+    mcu string,
+    fCpu int) error {
+
+    values, err := avrValues("toolchain/cmake/BaremetalToolchain.cmake", target, profile, projectName,
+        projectPath, cppStandard, cStandard)
+    if err != nil {
+        return err
+    }
+    values["MCU"] = mcu
+    values["F_CPU"] = strconv.Itoa(fCpu)
+    return generateCmakeLists("CMakeListsAVRBaremetal", buildPath, values)
+}
+
+// This creates the main CMakeLists.txt file for native targets in the given build path.
+// Profile and variant flags are added to the target and all of its dependencies
+func GenerateNativeCmakeLists(
+    target types.Target,
+    buildPath string,
+    profile types.Profile,
+    projectName string,
+    projectPath string,
+    cppStandard string,
+    cStandard string,
+    variantCompileFlags []string,
+    variantLinkFlags []string) error {
+
     flags := target.GetFlags().GetTarget()
     definitions := target.GetDefinitions().GetTarget()
-    buildPath := sys.Path(BuildPath(projectPath), target.GetName())
     templateFile := "CMakeListsNative"
 
-    return generateCmakeLists(templateFile, buildPath, map[string]string{
+    values := profileValues(profile)
+    for key, value := range map[string]string{
         "PROJECT_PATH":               filepath.ToSlash(projectPath),
         "PROJECT_NAME":               projectName,
         "CPP_STANDARD":               cppStandard,
@@ -140,5 +223,10 @@ func GenerateNativeCmakeLists(
         "ENTRY":                      target.GetSource(),
         "TARGET_COMPILE_FLAGS":       strings.Join(flags, " "),
         "TARGET_COMPILE_DEFINITIONS": strings.Join(definitions, " "),
-    })
+        "VARIANT_COMPILE_FLAGS":      strings.Join(variantCompileFlags, " "),
+        "VARIANT_LINK_FLAGS":         strings.Join(variantLinkFlags, " "),
+    } {
+        values[key] = value
+    }
+    return generateCmakeLists(templateFile, buildPath, values)
 }
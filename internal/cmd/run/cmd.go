@@ -1,27 +1,36 @@
 package run
 
 import (
+    "context"
     "fmt"
+    "io"
     "os"
     "os/exec"
-    "runtime"
     "strings"
+    "syscall"
+    "time"
+    "wio/internal/cmd/run/cmake"
     "wio/pkg/log"
+    "wio/pkg/util"
     "wio/pkg/util/sys"
 )
 
-func configTarget(dir string) error {
-    return Execute(dir, "cmake", "../", "-G", "Unix Makefiles")
+func configTarget(dir string, out io.Writer) error {
+    return ExecuteWriter(dir, out, "cmake", "../", "-G", "Unix Makefiles", "-DCMAKE_EXPORT_COMPILE_COMMANDS=ON")
 }
 
-func buildTarget(dir string) error {
-    jobs := runtime.NumCPU() + 2
+func buildTarget(dir string, jobs int, out io.Writer) error {
     jobsFlag := fmt.Sprintf("-j%d", jobs)
-    return Execute(dir, "make", jobsFlag)
+    return ExecuteWriter(dir, out, "make", jobsFlag)
 }
 
-func uploadTarget(dir string) error {
-    return Execute(dir, "make", "upload")
+// Uploads with the rule of the generated build, which reads the port from a make variable
+func uploadTarget(dir string, port string) error {
+    return Execute(dir, "make", uploadTargetArgs(port)...)
+}
+
+func uploadTargetArgs(port string) []string {
+    return []string{"upload", cmake.UploadPortVariable + "=" + port}
 }
 
 func runTarget(dir, file, args string) error {
@@ -32,38 +41,79 @@ func runTarget(dir, file, args string) error {
     return Execute(dir, file, argv...)
 }
 
-func cleanTarget(dir string) error {
-    return Execute(dir, "make", "clean")
+// Runs a test executable and returns its exit code and how long it took. An error is
+// only returned if the executable could not be run or did not finish in time
+func testTarget(dir, file, args string, timeout time.Duration, out io.Writer) (int, time.Duration, error) {
+    var argv []string
+    if args != "" {
+        argv = strings.Split(args, " ")
+    }
+    ctx := context.Background()
+    if timeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, timeout)
+        defer cancel()
+    }
+
+    cmd := exec.CommandContext(ctx, file, argv...)
+    cmd.Dir = dir
+    cmd.Stdout = out
+    cmd.Stderr = out
+    start := time.Now()
+    err := cmd.Run()
+    duration := time.Since(start)
+
+    if ctx.Err() == context.DeadlineExceeded {
+        return -1, duration, util.Error("timed out after %s", timeout)
+    }
+    if exitErr, ok := err.(*exec.ExitError); ok {
+        if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+            return status.ExitStatus(), duration, nil
+        }
+        return 1, duration, nil
+    }
+    return 0, duration, err
 }
 
-type targetFunc func(string, chan error)
+func cleanTarget(dir string, out io.Writer) error {
+    return ExecuteWriter(dir, out, "make", "clean")
+}
 
-func configAndBuild(dir string, errChan chan error) {
-    log.Verbln(log.Magenta, "Building directory: %s", dir)
+type targetFunc func(string, int, io.Writer) error
+
+func configAndBuild(dir string, jobs int, out io.Writer) error {
+    log.Verbln(log.Magenta, "Building directory: %s with JOBS=%d", dir, jobs)
+    binDir := sys.Path(dir, "bin")
+    if err := os.MkdirAll(binDir, os.ModePerm); err != nil {
+        return err
+    }
+    if err := configTarget(binDir, out); err != nil {
+        return err
+    }
+    return buildTarget(binDir, jobs, out)
+}
+
+func configOnly(dir string, _ int, out io.Writer) error {
+    log.Verbln(log.Magenta, "Configuring directory: %s", dir)
     binDir := sys.Path(dir, "bin")
     if err := os.MkdirAll(binDir, os.ModePerm); err != nil {
-        errChan <- err
-    } else if err := configTarget(binDir); err != nil {
-        errChan <- err
-    } else {
-        errChan <- buildTarget(binDir)
+        return err
     }
+    return configTarget(binDir, out)
 }
 
-func cleanIfExists(dir string, errChan chan error) {
+func cleanIfExists(dir string, _ int, out io.Writer) error {
     log.Verbln(log.Magenta, "Cleaning directory: %s", dir)
     binDir := sys.Path(dir, "bin")
-    exists := sys.Exists(binDir)
-    if exists {
-        errChan <- cleanTarget(binDir)
-    } else {
-        errChan <- nil
+    if !sys.Exists(binDir) {
+        return nil
     }
+    return cleanTarget(binDir, out)
 }
 
-func hardClean(dir string, errChan chan error) {
+func hardClean(dir string, _ int, _ io.Writer) error {
     log.Verbln(log.Magenta, "Removing directory: %s", dir)
-    errChan <- os.RemoveAll(dir)
+    return os.RemoveAll(dir)
 }
 
 func Execute(dir string, name string, args ...string) error {
@@ -74,3 +124,12 @@ func Execute(dir string, name string, args ...string) error {
     cmd.Stderr = os.Stderr
     return cmd.Run()
 }
+
+// Executes the command with both of its output streams sent to the writer
+func ExecuteWriter(dir string, out io.Writer, name string, args ...string) error {
+    cmd := exec.Command(name, args...)
+    cmd.Dir = dir
+    cmd.Stdout = out
+    cmd.Stderr = out
+    return cmd.Run()
+}
@@ -0,0 +1,208 @@
+package run
+
+import (
+    "encoding/json"
+    "path/filepath"
+    "strings"
+    "wio/internal/types"
+    "wio/pkg/log"
+    "wio/pkg/util/sys"
+)
+
+const compileCommandsFile = "compile_commands.json"
+
+// One entry of a JSON compilation database as exported by CMake
+type compileCommand struct {
+    Directory string   `json:"directory"`
+    Command   string   `json:"command,omitempty"`
+    Arguments []string `json:"arguments,omitempty"`
+    File      string   `json:"file"`
+    Output    string   `json:"output,omitempty"`
+}
+
+// Flags followed by a path, in the same argument or in the next one
+var pathFlags = []string{"-I", "-isystem", "-iquote", "-idirafter", "-L"}
+
+// Flags followed by a path in the next argument only
+var separatePathFlags = []string{"-o", "-include", "-imacros", "-MF"}
+
+// Flags followed by a value that is not a path in the next argument
+var valueFlags = []string{"-x", "-D", "-U", "-MT", "-MQ", "-arch", "-target", "-Xlinker", "-Xassembler", "-Xclang"}
+
+func containsFlag(flags []string, argument string) bool {
+    for _, flag := range flags {
+        if flag == argument {
+            return true
+        }
+    }
+    return false
+}
+
+// Splits a command line into its arguments the way a shell does, which is how CMake
+// quotes the command of an entry. Backslashes are only escapes where they are not the
+// path separator
+func splitCommand(command string) []string {
+    var arguments []string
+    var current []rune
+    inArgument := false
+    var quote rune
+    escaped := false
+    for _, char := range command {
+        switch {
+        case escaped:
+            current = append(current, char)
+            escaped = false
+        case char == '\\' && quote != '\'' && (filepath.Separator != '\\' || quote == '"'):
+            escaped = true
+            inArgument = true
+        case quote != 0:
+            if char == quote {
+                quote = 0
+            } else {
+                current = append(current, char)
+            }
+        case char == '"' || char == '\'':
+            quote = char
+            inArgument = true
+        case char == ' ' || char == '\t' || char == '\n':
+            if inArgument {
+                arguments = append(arguments, string(current))
+                current = current[:0]
+                inArgument = false
+            }
+        default:
+            current = append(current, char)
+            inArgument = true
+        }
+    }
+    if inArgument {
+        arguments = append(arguments, string(current))
+    }
+    return arguments
+}
+
+// Makes the include directories, sources and outputs in the arguments absolute
+func absoluteArguments(arguments []string, absolute func(string) string) []string {
+    rewritten := make([]string, 0, len(arguments))
+    pathNext, valueNext := false, false
+    for i, argument := range arguments {
+        switch {
+        case pathNext:
+            argument = absolute(argument)
+            pathNext = false
+        case valueNext:
+            valueNext = false
+        case i == 0:
+            // the compiler, which is only a path if it is not looked up in the path
+            if strings.ContainsAny(argument, "/\\") {
+                argument = absolute(argument)
+            }
+        case containsFlag(pathFlags, argument) || containsFlag(separatePathFlags, argument):
+            pathNext = true
+        case containsFlag(valueFlags, argument):
+            valueNext = true
+        case strings.HasPrefix(argument, "-"):
+            for _, flag := range pathFlags {
+                if strings.HasPrefix(argument, flag) {
+                    argument = flag + absolute(strings.TrimPrefix(argument, flag))
+                    break
+                }
+            }
+        default:
+            argument = absolute(argument)
+        }
+        rewritten = append(rewritten, argument)
+    }
+    return rewritten
+}
+
+// Makes every path of the entry absolute and clean and runs it from the project root, so
+// the database does not depend on the build directory it was generated in. This covers
+// project sources as well as dependency sources under .wio/node_modules and vendor. The
+// command is turned into a list of arguments to rewrite the paths in it
+func (command *compileCommand) normalize(root string) {
+    directory := filepath.Clean(command.Directory)
+    absolute := func(path string) string {
+        if !filepath.IsAbs(path) {
+            path = filepath.Join(directory, path)
+        }
+        return filepath.Clean(path)
+    }
+
+    command.File = absolute(command.File)
+    if command.Output != "" {
+        command.Output = absolute(command.Output)
+    }
+    arguments := command.Arguments
+    if len(arguments) == 0 {
+        arguments = splitCommand(command.Command)
+    }
+    command.Arguments = absoluteArguments(arguments, absolute)
+    command.Command = ""
+    command.Directory = filepath.Clean(root)
+}
+
+func readCompileCommands(path string, root string) ([]*compileCommand, error) {
+    var commands []*compileCommand
+    if err := sys.NormalIO.ParseJson(path, &commands); err != nil {
+        return nil, err
+    }
+    for _, command := range commands {
+        command.normalize(root)
+    }
+    return commands, nil
+}
+
+// Merges databases of several targets. When a file is compiled by more than one target,
+// the entry of the target listed first is kept
+func mergeCompileCommands(databases [][]*compileCommand) []*compileCommand {
+    merged := make([]*compileCommand, 0, 64)
+    seen := map[string]bool{}
+    for _, database := range databases {
+        for _, command := range database {
+            if seen[command.File] {
+                continue
+            }
+            seen[command.File] = true
+            merged = append(merged, command)
+        }
+    }
+    return merged
+}
+
+// Configures the targets and writes their merged compilation database to the project root
+func (info *runInfo) compdb(targets []types.Target) error {
+    log.Infoln(log.Cyan, "Generating files ... ")
+    targetDirs, err := configureTargets(info, targets)
+    if err != nil {
+        return err
+    }
+
+    log.Infoln(log.Cyan, "Configuring targets")
+    results := newScheduler(info.jobs, len(targetDirs), true, outputOptions{}).apply(configOnly, targetDirs)
+    if err := reportResults(results); err != nil {
+        return err
+    }
+
+    databases := make([][]*compileCommand, 0, len(targets))
+    for _, target := range targets {
+        database, err := readCompileCommands(sys.Path(binaryPath(info, target), compileCommandsFile),
+            info.directory)
+        if err != nil {
+            return err
+        }
+        databases = append(databases, database)
+    }
+
+    data, err := json.MarshalIndent(mergeCompileCommands(databases), "", "  ")
+    if err != nil {
+        return err
+    }
+    outputPath := sys.Path(info.directory, compileCommandsFile)
+    if err := sys.NormalIO.WriteFile(outputPath, append(data, '\n')); err != nil {
+        return err
+    }
+    log.Info(log.Cyan, "Compilation database written to ")
+    log.Infoln(log.Green, outputPath)
+    return nil
+}
@@ -0,0 +1,114 @@
+package run
+
+import (
+    "path/filepath"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestSplitCommand(t *testing.T) {
+    assert.Equal(t, []string{"c++", "-DNAME=\"wio\"", "-I/a b", "-c", "main.cpp"},
+        splitCommand(`c++  -DNAME=\"wio\" "-I/a b" -c main.cpp`))
+    assert.Equal(t, []string{"cc", "it's", ""}, splitCommand(`cc "it's" ''`))
+    assert.Equal(t, 0, len(splitCommand("")))
+}
+
+func TestNormalizeCompileCommand(t *testing.T) {
+    root := filepath.FromSlash("/project")
+    build := filepath.FromSlash("/project/.wio/targets/main/bin")
+    path := func(p string) string {
+        return filepath.FromSlash(p)
+    }
+
+    tests := []struct {
+        name      string
+        command   compileCommand
+        arguments []string
+        file      string
+        output    string
+    }{
+        {
+            name: "command with relative paths",
+            command: compileCommand{
+                Directory: build,
+                Command:   "/usr/bin/c++ -I../../../../src -isystem ../../../node_modules/lib/include -O2 -o out/main.o -c ../../../../src/main.cpp",
+                File:      "../../../../src/main.cpp",
+            },
+            arguments: []string{"/usr/bin/c++", "-I" + path("/project/src"), "-isystem",
+                path("/project/.wio/node_modules/lib/include"), "-O2", "-o",
+                path("/project/.wio/targets/main/bin/out/main.o"), "-c", path("/project/src/main.cpp")},
+            file: path("/project/src/main.cpp"),
+        },
+        {
+            name: "arguments with values that are not paths",
+            command: compileCommand{
+                Directory: build,
+                Arguments: []string{"avr-g++", "-x", "c++", "-D", "F_CPU=16000000UL", "-I", "include",
+                    "/project/src/app.cpp"},
+                File:   "/project/src/./app.cpp",
+                Output: "app.o",
+            },
+            arguments: []string{"avr-g++", "-x", "c++", "-D", "F_CPU=16000000UL", "-I",
+                path("/project/.wio/targets/main/bin/include"), path("/project/src/app.cpp")},
+            file:   path("/project/src/app.cpp"),
+            output: path("/project/.wio/targets/main/bin/app.o"),
+        },
+    }
+    for _, test := range tests {
+        command := test.command
+        command.normalize(root)
+        assert.Equal(t, test.arguments, command.Arguments, test.name)
+        assert.Equal(t, test.file, command.File, test.name)
+        assert.Equal(t, test.output, command.Output, test.name)
+        assert.Equal(t, "", command.Command, test.name)
+        assert.Equal(t, root, command.Directory, test.name)
+    }
+}
+
+func TestMergeCompileCommands(t *testing.T) {
+    entry := func(file string, directory string) *compileCommand {
+        return &compileCommand{File: file, Directory: directory}
+    }
+
+    tests := []struct {
+        name      string
+        databases [][]*compileCommand
+        expected  []*compileCommand
+    }{
+        {
+            name:      "empty",
+            databases: nil,
+            expected:  []*compileCommand{},
+        },
+        {
+            name: "distinct files keep their order",
+            databases: [][]*compileCommand{
+                {entry("/p/src/a.cpp", "main"), entry("/p/src/b.cpp", "main")},
+                {entry("/p/tests/t.cpp", "tests")},
+            },
+            expected: []*compileCommand{entry("/p/src/a.cpp", "main"), entry("/p/src/b.cpp", "main"),
+                entry("/p/tests/t.cpp", "tests")},
+        },
+        {
+            name: "the first target compiling a file wins",
+            databases: [][]*compileCommand{
+                {entry("/p/src/a.cpp", "main"), entry("/p/node_modules/lib/src/lib.cpp", "main")},
+                {entry("/p/node_modules/lib/src/lib.cpp", "tests"), entry("/p/src/a.cpp", "tests"),
+                    entry("/p/tests/t.cpp", "tests")},
+            },
+            expected: []*compileCommand{entry("/p/src/a.cpp", "main"),
+                entry("/p/node_modules/lib/src/lib.cpp", "main"), entry("/p/tests/t.cpp", "tests")},
+        },
+        {
+            name: "duplicates in one database",
+            databases: [][]*compileCommand{
+                {entry("/p/src/a.cpp", "first"), entry("/p/src/a.cpp", "second")},
+            },
+            expected: []*compileCommand{entry("/p/src/a.cpp", "first")},
+        },
+    }
+    for _, test := range tests {
+        assert.Equal(t, test.expected, mergeCompileCommands(test.databases), test.name)
+    }
+}
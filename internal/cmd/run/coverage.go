@@ -0,0 +1,157 @@
+package run
+
+import (
+    "io"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strings"
+    "wio/internal/cmd/run/coverage"
+    "wio/internal/constants"
+    "wio/internal/types"
+    "wio/pkg/log"
+    "wio/pkg/util"
+    "wio/pkg/util/sys"
+)
+
+const coverageDir = "coverage"
+
+var compilerIdPattern = regexp.MustCompile(`set\(CMAKE_CXX_COMPILER_ID "(\w+)"\)`)
+
+// Coverage data files written when an instrumented executable exits
+func findCoverageData(binDir string) ([]string, error) {
+    files := make([]string, 0, 16)
+    err := filepath.Walk(binDir, func(path string, info os.FileInfo, err error) error {
+        if err != nil {
+            return err
+        }
+        if !info.IsDir() && filepath.Ext(path) == ".gcda" {
+            files = append(files, path)
+        }
+        return nil
+    })
+    return files, err
+}
+
+// Removes the counts of previous runs so the report only covers this one
+func resetCoverage(info *runInfo, target types.Target) error {
+    files, err := findCoverageData(binaryPath(info, target))
+    if err != nil {
+        return err
+    }
+    for _, file := range files {
+        if err := os.Remove(file); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// Command that turns coverage data into .gcov files. Clang writes data only
+// llvm-cov understands, anything else is handled by gcov
+func gcovCommand(info *runInfo, binDir string) []string {
+    if command := strings.TrimSpace(info.context.String("gcov")); command != "" {
+        return strings.Fields(command)
+    }
+    compilerFiles, _ := filepath.Glob(sys.Path(binDir, "CMakeFiles", "*", "CMakeCXXCompiler.cmake"))
+    for _, file := range compilerFiles {
+        data, err := ioutil.ReadFile(file)
+        if err != nil {
+            continue
+        }
+        if match := compilerIdPattern.FindSubmatch(data); match != nil && strings.Contains(string(match[1]), "Clang") {
+            return []string{"llvm-cov", "gcov"}
+        }
+    }
+    return []string{"gcov"}
+}
+
+// Runs gcov on the coverage data of the target and adds the results to the report.
+// Every data file is processed on its own since gcov names its output after the
+// source, which would overwrite the counts of headers included by several objects
+func collectCoverage(info *runInfo, target types.Target, report *coverage.Report) error {
+    binDir := binaryPath(info, target)
+    dataFiles, err := findCoverageData(binDir)
+    if err != nil {
+        return err
+    }
+    if len(dataFiles) <= 0 {
+        // executables that crash or are stopped never write their counts
+        log.Warnln("no coverage data found for target %s", target.GetName())
+        return nil
+    }
+
+    command := gcovCommand(info, binDir)
+    outDir := sys.Path(targetPath(info, target), coverageDir)
+    defer os.RemoveAll(outDir)
+
+    for _, dataFile := range dataFiles {
+        if err := os.RemoveAll(outDir); err != nil {
+            return err
+        }
+        if err := os.MkdirAll(outDir, os.ModePerm); err != nil {
+            return err
+        }
+        args := append(append([]string{}, command[1:]...), "-p", dataFile)
+        log.Verbln(log.Magenta, "%s %s", command[0], strings.Join(args, " "))
+        if err := ExecuteWriter(outDir, ioutil.Discard, command[0], args...); err != nil {
+            return util.Error("%s failed for %s: %s", command[0], dataFile, err.Error())
+        }
+
+        gcovFiles, err := filepath.Glob(sys.Path(outDir, "*.gcov"))
+        if err != nil {
+            return err
+        }
+        for _, gcovFile := range gcovFiles {
+            data, err := ioutil.ReadFile(gcovFile)
+            if err != nil {
+                return err
+            }
+            report.Parse(string(data), binDir)
+        }
+    }
+    return nil
+}
+
+// Writes the lcov and Cobertura reports and logs the coverage of each file
+func writeCoverage(info *runInfo, report *coverage.Report) error {
+    report.Log()
+
+    outDir := info.context.String("coverage-output")
+    if outDir == "" {
+        outDir = sys.Path(info.directory, sys.Folder, coverageDir)
+    }
+    if err := os.MkdirAll(outDir, os.ModePerm); err != nil {
+        return err
+    }
+
+    if err := writeReportFile(sys.Path(outDir, "lcov.info"), report.WriteLcov); err != nil {
+        return err
+    }
+    if err := writeReportFile(sys.Path(outDir, "cobertura.xml"), report.WriteCobertura); err != nil {
+        return err
+    }
+    log.Info(log.Cyan, "Coverage reports written to ")
+    log.Infoln(log.Green, outDir)
+    return nil
+}
+
+// Creates the file and writes the report to it. The file is only complete once closed, so
+// an error closing it is returned too
+func writeReportFile(path string, write func(io.Writer) error) error {
+    file, err := os.Create(path)
+    if err != nil {
+        return err
+    }
+    if err := write(file); err != nil {
+        file.Close()
+        return err
+    }
+    return file.Close()
+}
+
+// Only native targets are instrumented, coverage of AVR firmware is not supported
+func isCoverageTarget(info *runInfo, target types.Target) bool {
+    return info.variant != nil && info.variant.coverage && target.GetPlatform() == constants.Native
+}
@@ -0,0 +1,208 @@
+// Copyright 2018 Waterloop. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package coverage parses the .gcov files produced by gcov and llvm-cov and
+// aggregates the line coverage of several targets into one report
+package coverage
+
+import (
+    "bytes"
+    "fmt"
+    "path"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "text/tabwriter"
+    "wio/pkg/log"
+)
+
+// count:line:source, where the count is "-" for lines without code and
+// "#####" or "=====" for lines that were never executed
+var linePattern = regexp.MustCompile(`^\s*([^:]+?):\s*(\d+):`)
+
+var sourcePattern = regexp.MustCompile(`^\s*-:\s*0:Source:(.*)$`)
+
+// Line coverage of one source file
+type File struct {
+    // path relative to the project
+    Path  string
+    Lines map[int]int
+}
+
+// Number of lines with code
+func (f *File) Valid() int {
+    return len(f.Lines)
+}
+
+// Number of lines with code that were executed at least once
+func (f *File) Covered() int {
+    covered := 0
+    for _, hits := range f.Lines {
+        if hits > 0 {
+            covered++
+        }
+    }
+    return covered
+}
+
+// Line numbers with code in ascending order
+func (f *File) LineNumbers() []int {
+    numbers := make([]int, 0, len(f.Lines))
+    for number := range f.Lines {
+        numbers = append(numbers, number)
+    }
+    sort.Ints(numbers)
+    return numbers
+}
+
+func rate(covered int, valid int) float64 {
+    if valid <= 0 {
+        return 0
+    }
+    return float64(covered) / float64(valid)
+}
+
+func (f *File) Rate() float64 {
+    return rate(f.Covered(), f.Valid())
+}
+
+// Coverage of all the project sources. Counts of a file compiled into several
+// objects or targets are added up
+type Report struct {
+    ProjectPath string
+    includeDeps bool
+    files       map[string]*File
+}
+
+func NewReport(projectPath string, includeDeps bool) *Report {
+    return &Report{
+        ProjectPath: slashPath(projectPath),
+        includeDeps: includeDeps,
+        files:       map[string]*File{},
+    }
+}
+
+func slashPath(file string) string {
+    return strings.Replace(filepath.Clean(file), "\\", "/", -1)
+}
+
+// Maps the source path of a .gcov file to a path relative to the project. Sources outside
+// the project, such as system headers, and dependency sources unless included, are dropped
+func (report *Report) mapPath(source string, baseDir string) (string, bool) {
+    source = slashPath(source)
+    if !path.IsAbs(source) && !filepath.IsAbs(source) {
+        source = path.Join(slashPath(baseDir), source)
+    }
+    if !strings.HasPrefix(source, report.ProjectPath+"/") {
+        return "", false
+    }
+    relative := strings.TrimPrefix(source, report.ProjectPath+"/")
+    if !report.includeDeps && (strings.HasPrefix(relative, "node_modules/") ||
+        strings.Contains(relative, "/node_modules/")) {
+        return "", false
+    }
+    return relative, true
+}
+
+// Adds the counts of one .gcov file. Relative source paths are resolved against the
+// directory the sources were compiled in
+func (report *Report) Parse(data string, baseDir string) {
+    var file *File
+    last := 0
+
+    for _, line := range strings.Split(data, "\n") {
+        line = strings.TrimRight(line, "\r")
+        if match := sourcePattern.FindStringSubmatch(line); match != nil {
+            relative, ok := report.mapPath(strings.TrimSpace(match[1]), baseDir)
+            if !ok {
+                return
+            }
+            if file = report.files[relative]; file == nil {
+                file = &File{Path: relative, Lines: map[int]int{}}
+                report.files[relative] = file
+            }
+            continue
+        }
+
+        match := linePattern.FindStringSubmatch(line)
+        if file == nil || match == nil {
+            continue
+        }
+        number, _ := strconv.Atoi(match[2])
+        // blocks of template instantiations repeat lines that were already counted
+        if number <= last {
+            continue
+        }
+        last = number
+
+        count := strings.TrimSuffix(match[1], "*")
+        switch count {
+        case "-":
+            continue
+        case "#####", "=====":
+            if _, exists := file.Lines[number]; !exists {
+                file.Lines[number] = 0
+            }
+        default:
+            hits, err := strconv.Atoi(count)
+            if err != nil {
+                continue
+            }
+            file.Lines[number] += hits
+        }
+    }
+}
+
+// Files sorted by path
+func (report *Report) Files() []*File {
+    files := make([]*File, 0, len(report.files))
+    for _, file := range report.files {
+        files = append(files, file)
+    }
+    sort.Slice(files, func(i, j int) bool {
+        return files[i].Path < files[j].Path
+    })
+    return files
+}
+
+// Total number of covered and valid lines
+func (report *Report) Total() (int, int) {
+    covered, valid := 0, 0
+    for _, file := range report.files {
+        covered += file.Covered()
+        valid += file.Valid()
+    }
+    return covered, valid
+}
+
+func (report *Report) Rate() float64 {
+    return rate(report.Total())
+}
+
+func percent(value float64) string {
+    return fmt.Sprintf("%.1f%%", value*100)
+}
+
+// Logs a table with the coverage of each file
+func (report *Report) Log() {
+    buf := &bytes.Buffer{}
+    table := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(table, "FILE\tLINES\tCOVERED\tPERCENT")
+    for _, file := range report.Files() {
+        fmt.Fprintf(table, "%s\t%d\t%d\t%s\n", file.Path, file.Valid(), file.Covered(), percent(file.Rate()))
+    }
+    covered, valid := report.Total()
+    fmt.Fprintf(table, "TOTAL\t%d\t%d\t%s\n", valid, covered, percent(report.Rate()))
+    table.Flush()
+
+    lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+    log.Infoln()
+    log.Infoln(log.Cyan, "%s", lines[0])
+    for _, line := range lines[1 : len(lines)-1] {
+        log.Infoln("%s", line)
+    }
+    log.Infoln(log.Cyan, "%s", lines[len(lines)-1])
+}
@@ -0,0 +1,109 @@
+package coverage
+
+import (
+    "bytes"
+    "encoding/xml"
+    "strings"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+const mainGcov = `        -:    0:Source:/home/dev/app/src/main.cpp
+        -:    0:Graph:/home/dev/app/.wio/targets/tests-coverage/bin/CMakeFiles/tests.dir/src/main.cpp.gcno
+        -:    0:Data:/home/dev/app/.wio/targets/tests-coverage/bin/CMakeFiles/tests.dir/src/main.cpp.gcda
+        -:    0:Runs:1
+        -:    1:#include <cstdio>
+        2:    2:template <typename T> T twice(T v) {
+        2:    3:    return v * 2;
+        -:    4:}
+------------------
+_Z5twiceIdET_S0_:
+        1:    2:template <typename T> T twice(T v) {
+        1:    3:    return v * 2;
+        -:    4:}
+------------------
+    #####:    5:int unused() {
+    =====:    6:    return 3;
+        -:    7:}
+        1*:   8:int main() {
+        1:    9:    if (twice(1) == 2) printf("ok\n");
+        1:   10:    return 0;
+        -:   11:}
+`
+
+const headerGcov = `        -:    0:Source:../../../../../../include/util.h
+        -:    0:Runs:1
+        3:    4:inline int inc(int x) {
+    #####:    5:    return x + 1;
+`
+
+const depGcov = `        -:    0:Source:/home/dev/app/.wio/node_modules/pkg-list__0.0.1/src/list.cpp
+        5:    1:int list() {
+`
+
+const systemGcov = `        -:    0:Source:/usr/include/c++/12/bits/stl_vector.h
+        5:    1:int vector() {
+`
+
+func TestReport_Parse(t *testing.T) {
+    report := NewReport("/home/dev/app", false)
+    report.Parse(mainGcov, "/home/dev/app/.wio/targets/tests-coverage/bin")
+    report.Parse(depGcov, "/home/dev/app/.wio/targets/tests-coverage/bin")
+    report.Parse(systemGcov, "/home/dev/app/.wio/targets/tests-coverage/bin")
+
+    files := report.Files()
+    assert.Equal(t, 1, len(files))
+    assert.Equal(t, "src/main.cpp", files[0].Path)
+    assert.Equal(t, map[int]int{2: 2, 3: 2, 5: 0, 6: 0, 8: 1, 9: 1, 10: 1}, files[0].Lines)
+    assert.Equal(t, 7, files[0].Valid())
+    assert.Equal(t, 5, files[0].Covered())
+    assert.Equal(t, []int{2, 3, 5, 6, 8, 9, 10}, files[0].LineNumbers())
+}
+
+func TestReport_ParseMerges(t *testing.T) {
+    report := NewReport("/home/dev/app", true)
+    baseDir := "/home/dev/app/.wio/targets/tests-coverage/bin/CMakeFiles/tests.dir"
+    report.Parse(headerGcov, baseDir)
+    report.Parse(strings.Replace(headerGcov, "#####", "    2", 1), baseDir)
+    report.Parse(depGcov, baseDir)
+
+    files := report.Files()
+    assert.Equal(t, 2, len(files))
+    assert.Equal(t, ".wio/node_modules/pkg-list__0.0.1/src/list.cpp", files[0].Path)
+    assert.Equal(t, "include/util.h", files[1].Path)
+    assert.Equal(t, map[int]int{4: 6, 5: 2}, files[1].Lines)
+
+    covered, valid := report.Total()
+    assert.Equal(t, 3, covered)
+    assert.Equal(t, 3, valid)
+}
+
+func TestReport_WriteLcov(t *testing.T) {
+    report := NewReport("/home/dev/app", false)
+    report.Parse(headerGcov, "/home/dev/app/.wio/targets/tests-coverage/bin/CMakeFiles/tests.dir")
+
+    buf := &bytes.Buffer{}
+    assert.Nil(t, report.WriteLcov(buf))
+    assert.Equal(t, "TN:\nSF:/home/dev/app/include/util.h\nDA:4,3\nDA:5,0\nLF:2\nLH:1\nend_of_record\n",
+        buf.String())
+}
+
+func TestReport_WriteCobertura(t *testing.T) {
+    report := NewReport("/home/dev/app", false)
+    report.Parse(mainGcov, "/home/dev/app")
+    report.Parse(headerGcov, "/home/dev/app/.wio/targets/tests-coverage/bin/CMakeFiles/tests.dir")
+
+    buf := &bytes.Buffer{}
+    assert.Nil(t, report.WriteCobertura(buf))
+
+    var decoded coberturaReport
+    assert.Nil(t, xml.Unmarshal(buf.Bytes(), &decoded))
+    assert.Equal(t, 6, decoded.LinesCovered)
+    assert.Equal(t, 9, decoded.LinesValid)
+    assert.Equal(t, 2, len(decoded.Packages))
+    assert.Equal(t, "include", decoded.Packages[0].Name)
+    assert.Equal(t, "0.5000", decoded.Packages[0].LineRate)
+    assert.Equal(t, "src/main.cpp", decoded.Packages[1].Classes[0].Filename)
+    assert.Equal(t, 7, len(decoded.Packages[1].Classes[0].Lines))
+}
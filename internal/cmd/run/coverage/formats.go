@@ -0,0 +1,131 @@
+package coverage
+
+import (
+    "bufio"
+    "encoding/xml"
+    "fmt"
+    "io"
+    "path"
+    "strconv"
+    "time"
+)
+
+// Writes the report as an lcov tracefile with absolute source paths
+func (report *Report) WriteLcov(writer io.Writer) error {
+    buf := bufio.NewWriter(writer)
+    for _, file := range report.Files() {
+        fmt.Fprintln(buf, "TN:")
+        fmt.Fprintf(buf, "SF:%s\n", path.Join(report.ProjectPath, file.Path))
+        for _, number := range file.LineNumbers() {
+            fmt.Fprintf(buf, "DA:%d,%d\n", number, file.Lines[number])
+        }
+        fmt.Fprintf(buf, "LF:%d\n", file.Valid())
+        fmt.Fprintf(buf, "LH:%d\n", file.Covered())
+        fmt.Fprintln(buf, "end_of_record")
+    }
+    return buf.Flush()
+}
+
+type coberturaLine struct {
+    Number int `xml:"number,attr"`
+    Hits   int `xml:"hits,attr"`
+}
+
+type coberturaClass struct {
+    Name       string          `xml:"name,attr"`
+    Filename   string          `xml:"filename,attr"`
+    LineRate   string          `xml:"line-rate,attr"`
+    BranchRate string          `xml:"branch-rate,attr"`
+    Complexity string          `xml:"complexity,attr"`
+    Methods    struct{}        `xml:"methods"`
+    Lines      []coberturaLine `xml:"lines>line"`
+}
+
+type coberturaPackage struct {
+    Name       string           `xml:"name,attr"`
+    LineRate   string           `xml:"line-rate,attr"`
+    BranchRate string           `xml:"branch-rate,attr"`
+    Complexity string           `xml:"complexity,attr"`
+    Classes    []coberturaClass `xml:"classes>class"`
+}
+
+type coberturaReport struct {
+    XMLName         xml.Name           `xml:"coverage"`
+    LineRate        string             `xml:"line-rate,attr"`
+    BranchRate      string             `xml:"branch-rate,attr"`
+    LinesCovered    int                `xml:"lines-covered,attr"`
+    LinesValid      int                `xml:"lines-valid,attr"`
+    BranchesCovered int                `xml:"branches-covered,attr"`
+    BranchesValid   int                `xml:"branches-valid,attr"`
+    Complexity      string             `xml:"complexity,attr"`
+    Version         string             `xml:"version,attr"`
+    Timestamp       int64              `xml:"timestamp,attr"`
+    Sources         []string           `xml:"sources>source"`
+    Packages        []coberturaPackage `xml:"packages>package"`
+}
+
+func formatRate(value float64) string {
+    return strconv.FormatFloat(value, 'f', 4, 64)
+}
+
+// Writes the report in the Cobertura XML format. Files are grouped in a package
+// per directory and only line coverage is reported
+func (report *Report) WriteCobertura(writer io.Writer) error {
+    covered, valid := report.Total()
+    result := coberturaReport{
+        LineRate:     formatRate(report.Rate()),
+        BranchRate:   formatRate(0),
+        LinesCovered: covered,
+        LinesValid:   valid,
+        Complexity:   "0",
+        Version:      "wio",
+        Timestamp:    time.Now().Unix(),
+        Sources:      []string{report.ProjectPath},
+    }
+
+    packages := map[string]int{}
+    packageTotals := map[string][2]int{}
+    for _, file := range report.Files() {
+        dir := path.Dir(file.Path)
+        index, exists := packages[dir]
+        if !exists {
+            index = len(result.Packages)
+            packages[dir] = index
+            result.Packages = append(result.Packages, coberturaPackage{
+                Name:       dir,
+                BranchRate: formatRate(0),
+                Complexity: "0",
+            })
+        }
+
+        class := coberturaClass{
+            Name:       file.Path,
+            Filename:   file.Path,
+            LineRate:   formatRate(file.Rate()),
+            BranchRate: formatRate(0),
+            Complexity: "0",
+        }
+        for _, number := range file.LineNumbers() {
+            class.Lines = append(class.Lines, coberturaLine{Number: number, Hits: file.Lines[number]})
+        }
+        result.Packages[index].Classes = append(result.Packages[index].Classes, class)
+
+        totals := packageTotals[dir]
+        packageTotals[dir] = [2]int{totals[0] + file.Covered(), totals[1] + file.Valid()}
+    }
+    for i := range result.Packages {
+        totals := packageTotals[result.Packages[i].Name]
+        result.Packages[i].LineRate = formatRate(rate(totals[0], totals[1]))
+    }
+
+    if _, err := io.WriteString(writer, xml.Header); err != nil {
+        return err
+    }
+    encoder := xml.NewEncoder(writer)
+    encoder.Indent("", "  ")
+    if err := encoder.Encode(result); err != nil {
+        return err
+    }
+    _, err := io.WriteString(writer, "\n")
+    return err
+}
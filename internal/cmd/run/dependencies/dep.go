@@ -22,12 +22,26 @@ var libraryStrings = map[string]map[bool]string{
     "native": {false: cmake.DesktopLibrary, true: cmake.DesktopHeader},
 }
 
+// Frameworks that build their libraries differently from the rest of the platform
+var frameworkLibraryStrings = map[string]map[bool]string{
+    constants.Baremetal: {false: cmake.AvrBaremetalLibrary, true: cmake.AvrHeader},
+}
+
+// CMake string of a dependency for the platform and framework of the target
+func libraryString(platform string, framework string, headerOnly bool) string {
+    framework = strings.ToLower(strings.Trim(framework, " "))
+    if frameworkStrings, exists := frameworkLibraryStrings[framework]; exists {
+        return frameworkStrings[headerOnly]
+    }
+    return libraryStrings[platform][headerOnly]
+}
+
 // This creates CMake dependency string using build targets that will be used to link dependencies
-func GenerateCMakeDependencies(cmakePath string, platform string, targets *TargetSet) error {
+func GenerateCMakeDependencies(cmakePath string, platform string, framework string, targets *TargetSet) error {
     cmakeStrings := make([]string, 0, 256)
 
     for target := range targets.TargetIterator() {
-        finalString := libraryStrings[platform][target.HeaderOnly]
+        finalString := libraryString(platform, framework, target.HeaderOnly)
 
         finalString = template.Replace(finalString, map[string]string{
             "DEPENDENCY_PATH":     filepath.ToSlash(target.Path),
@@ -0,0 +1,64 @@
+package run
+
+import (
+    "io"
+    "regexp"
+    "strings"
+    "time"
+    "wio/internal/cmd/devices"
+    "wio/internal/cmd/run/tests"
+    "wio/internal/types"
+    "wio/pkg/log"
+)
+
+// Reads the output of test firmware from the port until the sentinel line is received.
+// Any serial device works here, which lets a pseudo-terminal stand in for the board
+func readDeviceOutput(port string, baud int, sentinel *regexp.Regexp, timeout time.Duration,
+    out io.Writer) (string, string, error) {
+    serialPort, err := devices.OpenInterruptiblePort(port, baud)
+    if err != nil {
+        return "", "", err
+    }
+    return tests.ReadUntil(serialPort, sentinel, timeout, out)
+}
+
+// Uploads test firmware to the board and collects the results it prints over serial
+func runDeviceTest(info *runInfo, target types.Target, index int, suite *tests.Suite) error {
+    pattern := info.context.String("sentinel")
+    if pattern == "" {
+        pattern = tests.DefaultSentinel
+    }
+    sentinel, err := regexp.Compile(pattern)
+    if err != nil {
+        return err
+    }
+    port, err := monitorPort(info, target)
+    if err != nil {
+        return err
+    }
+
+    log.Info(log.Cyan, "Uploading ")
+    log.Info(log.Magenta, target.GetName())
+    log.Infoln(log.Cyan, " to %s", port)
+    if err := uploadFirmware(info, target); err != nil {
+        return err
+    }
+
+    output, err := outputOptions{group: info.context.Bool("group-output")}.open(index, targetPath(info, target))
+    if err != nil {
+        return err
+    }
+    start := time.Now()
+    text, last, err := readDeviceOutput(port, monitorBaud(info, target), sentinel,
+        info.context.Duration("timeout"), output)
+    suite.Duration = time.Since(start)
+    suite.Output = text
+    suite.Cases = tests.Parse(text)
+    if err != nil {
+        suite.Error = err.Error()
+    } else if strings.TrimSpace(last) == "FAIL" {
+        // firmware reported failure without any case we could parse
+        suite.ExitCode = 1
+    }
+    return output.Close(testStatuses[suite.Status()])
+}
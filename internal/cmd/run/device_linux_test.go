@@ -0,0 +1,72 @@
+package run
+
+import (
+    "bytes"
+    "fmt"
+    "os"
+    "regexp"
+    "syscall"
+    "testing"
+    "time"
+    "unsafe"
+    "wio/internal/cmd/run/tests"
+
+    "github.com/stretchr/testify/assert"
+)
+
+// Opens a pseudo-terminal pair. The slave end stands in for the board's serial port
+func openPty(t *testing.T) (*os.File, string) {
+    master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+    if err != nil {
+        t.Skip("pseudo-terminals are not available")
+    }
+    unlock := int32(0)
+    if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCSPTLCK,
+        uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+        t.Fatal(errno)
+    }
+    number := uint32(0)
+    if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCGPTN,
+        uintptr(unsafe.Pointer(&number))); errno != 0 {
+        t.Fatal(errno)
+    }
+    return master, fmt.Sprintf("/dev/pts/%d", number)
+}
+
+func TestReadDeviceOutput(t *testing.T) {
+    master, slave := openPty(t)
+    defer master.Close()
+
+    go func() {
+        time.Sleep(100 * time.Millisecond)
+        master.WriteString("booting\r\n")
+        master.WriteString("tests/main.cpp:10:test_push:PASS\r\n")
+        master.WriteString("tests/main.cpp:20:test_pop:FAIL: Expected 2 Was 3\r\n")
+        master.WriteString("2 Tests 1 Failures 0 Ignored\r\nFAIL\r\n")
+        master.WriteString("never read\r\n")
+    }()
+
+    out := &bytes.Buffer{}
+    sentinel := regexp.MustCompile(tests.DefaultSentinel)
+    text, last, err := readDeviceOutput(slave, 9600, sentinel, 5*time.Second, out)
+    assert.Nil(t, err)
+    assert.Equal(t, "FAIL", last)
+    assert.Equal(t, text, out.String())
+    assert.NotContains(t, text, "never read")
+
+    cases := tests.Parse(text)
+    assert.Equal(t, 2, len(cases))
+    assert.Equal(t, tests.Fail, cases[1].Status)
+}
+
+func TestReadDeviceOutput_Timeout(t *testing.T) {
+    master, slave := openPty(t)
+    defer master.Close()
+
+    go master.WriteString("tests/main.cpp:10:test_push:PASS\r\n")
+
+    sentinel := regexp.MustCompile(tests.DefaultSentinel)
+    text, _, err := readDeviceOutput(slave, 9600, sentinel, 500*time.Millisecond, nil)
+    assert.NotNil(t, err)
+    assert.Contains(t, text, "test_push:PASS")
+}
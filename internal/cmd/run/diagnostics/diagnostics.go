@@ -0,0 +1,209 @@
+// Copyright 2018 Waterloop. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package diagnostics parses GCC/Clang diagnostics from the output of target builds
+// and reports them grouped and deduplicated
+package diagnostics
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "wio/pkg/log"
+
+    "github.com/fatih/color"
+)
+
+const (
+    Error   = "error"
+    Warning = "warning"
+    Note    = "note"
+)
+
+// file:line:col: severity: message, where the column is optional
+var diagnosticPattern = regexp.MustCompile(
+    `^(.+?):(\d+):(?:(\d+):)?\s*(fatal error|error|warning|note):\s*(.*)$`)
+
+// path of a source file inside an installed dependency: node_modules/<name>__<version>/<file>
+var modulePattern = regexp.MustCompile(`(?:^|/)node_modules/([^/]+)__([^/]+)/(.+)$`)
+
+var severityOrder = map[string]int{
+    Error:   0,
+    Warning: 1,
+    Note:    2,
+}
+
+var severityColors = map[string]*color.Color{
+    Error:   log.Red,
+    Warning: log.Yellow,
+    Note:    log.Cyan,
+}
+
+type Diagnostic struct {
+    File     string   `json:"file"`
+    Package  string   `json:"package,omitempty"`
+    Line     int      `json:"line"`
+    Column   int      `json:"column,omitempty"`
+    Severity string   `json:"severity"`
+    Message  string   `json:"message"`
+    Targets  []string `json:"targets"`
+}
+
+// Location of the diagnostic, prefixed with the package it belongs to
+func (d *Diagnostic) Location() string {
+    location := d.File + ":" + strconv.Itoa(d.Line)
+    if d.Column > 0 {
+        location += ":" + strconv.Itoa(d.Column)
+    }
+    if d.Package != "" {
+        location = d.Package + " " + location
+    }
+    return location
+}
+
+func (d *Diagnostic) key() string {
+    return fmt.Sprintf("%s|%s|%d|%d|%s|%s", d.Package, d.File, d.Line, d.Column, d.Severity, d.Message)
+}
+
+// Set of diagnostics collected from the builds of one or more targets.
+// The same diagnostic reported by several targets is only kept once
+type Set struct {
+    projectPath string
+    list        []*Diagnostic
+    index       map[string]*Diagnostic
+}
+
+func NewSet(projectPath string) *Set {
+    return &Set{
+        projectPath: slashPath(projectPath),
+        index:       map[string]*Diagnostic{},
+    }
+}
+
+// Compilers on windows report paths with backslashes no matter where wio runs
+func slashPath(path string) string {
+    return strings.Replace(filepath.Clean(path), "\\", "/", -1)
+}
+
+// Maps a path reported by the compiler to a package and a path relative to it.
+// Sources of the project itself are made relative to the project directory
+func (set *Set) mapPath(file string) (string, string) {
+    file = slashPath(file)
+    if match := modulePattern.FindStringSubmatch(file); match != nil {
+        return match[1] + "@" + match[2], match[3]
+    }
+    if set.projectPath != "" && strings.HasPrefix(file, set.projectPath+"/") {
+        return "", strings.TrimPrefix(file, set.projectPath+"/")
+    }
+    return "", file
+}
+
+// Parses one line of compiler output and returns nil if it is not a diagnostic
+func (set *Set) parseLine(line string) *Diagnostic {
+    match := diagnosticPattern.FindStringSubmatch(strings.TrimRight(line, "\r"))
+    if match == nil {
+        return nil
+    }
+    lineNumber, _ := strconv.Atoi(match[2])
+    column, _ := strconv.Atoi(match[3])
+    severity := match[4]
+    if severity == "fatal error" {
+        severity = Error
+    }
+    pkg, file := set.mapPath(match[1])
+    return &Diagnostic{
+        File:     file,
+        Package:  pkg,
+        Line:     lineNumber,
+        Column:   column,
+        Severity: severity,
+        Message:  strings.TrimSpace(match[5]),
+    }
+}
+
+// Adds all the diagnostics found in the build output of the target
+func (set *Set) Parse(target string, output string) {
+lines:
+    for _, line := range strings.Split(output, "\n") {
+        diagnostic := set.parseLine(line)
+        if diagnostic == nil {
+            continue
+        }
+        if existing, exists := set.index[diagnostic.key()]; exists {
+            for _, name := range existing.Targets {
+                if name == target {
+                    continue lines
+                }
+            }
+            existing.Targets = append(existing.Targets, target)
+        } else {
+            diagnostic.Targets = []string{target}
+            set.index[diagnostic.key()] = diagnostic
+            set.list = append(set.list, diagnostic)
+        }
+    }
+}
+
+// Diagnostics sorted by severity and then by location
+func (set *Set) List() []*Diagnostic {
+    list := make([]*Diagnostic, len(set.list))
+    copy(list, set.list)
+    sort.SliceStable(list, func(i, j int) bool {
+        a, b := list[i], list[j]
+        if severityOrder[a.Severity] != severityOrder[b.Severity] {
+            return severityOrder[a.Severity] < severityOrder[b.Severity]
+        }
+        if a.Package != b.Package {
+            return a.Package < b.Package
+        }
+        if a.File != b.File {
+            return a.File < b.File
+        }
+        return a.Line < b.Line
+    })
+    return list
+}
+
+// Number of diagnostics with the given severity
+func (set *Set) Count(severity string) int {
+    count := 0
+    for _, diagnostic := range set.list {
+        if diagnostic.Severity == severity {
+            count++
+        }
+    }
+    return count
+}
+
+// Logs a colorized summary of the diagnostics
+func (set *Set) Log() {
+    if len(set.list) <= 0 {
+        return
+    }
+    log.Infoln()
+    for _, diagnostic := range set.List() {
+        log.Info(severityColors[diagnostic.Severity], "%-8s", diagnostic.Severity)
+        log.Info(log.Cyan, "%s", diagnostic.Location())
+        log.Info(": %s ", diagnostic.Message)
+        log.Infoln(log.Magenta, "[%s]", strings.Join(diagnostic.Targets, ", "))
+    }
+    log.Info(log.Red, "%d errors", set.Count(Error))
+    log.Info(", ")
+    log.Infoln(log.Yellow, "%d warnings", set.Count(Warning))
+}
+
+// Writes the diagnostics as a JSON array
+func (set *Set) WriteJson(writer io.Writer) error {
+    data, err := json.MarshalIndent(set.List(), "", "  ")
+    if err != nil {
+        return err
+    }
+    _, err = writer.Write(append(data, '\n'))
+    return err
+}
@@ -0,0 +1,76 @@
+package diagnostics
+
+import (
+    "bytes"
+    "encoding/json"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+const buildOutput = `Scanning dependencies of target main
+[ 50%] Building CXX object CMakeFiles/main.dir/src/main.cpp.o
+/home/dev/app/src/main.cpp: In function 'int main()':
+/home/dev/app/src/main.cpp:12:5: error: 'foo' was not declared in this scope
+     foo();
+     ^~~
+/home/dev/app/.wio/node_modules/pkg-list__0.0.1/include/pkg-list.h:3:1: warning: unused variable 'x' [-Wunused-variable]
+/home/dev/app/src/main.cpp:20: note: declared here
+C:\dev\app\src\impl.c:7:2: fatal error: missing.h: No such file or directory
+make[2]: *** [CMakeFiles/main.dir/src/main.cpp.o] Error 1
+`
+
+func TestSet_Parse(t *testing.T) {
+    set := NewSet("/home/dev/app")
+    set.Parse("main", buildOutput)
+
+    list := set.List()
+    assert.Equal(t, 4, len(list))
+    assert.Equal(t, 2, set.Count(Error))
+    assert.Equal(t, 1, set.Count(Warning))
+    assert.Equal(t, 1, set.Count(Note))
+
+    assert.Equal(t, "src/main.cpp", list[1].File)
+    assert.Equal(t, 12, list[1].Line)
+    assert.Equal(t, 5, list[1].Column)
+    assert.Equal(t, "'foo' was not declared in this scope", list[1].Message)
+
+    assert.Equal(t, Error, list[0].Severity)
+    assert.Equal(t, "C:/dev/app/src/impl.c", list[0].File)
+
+    assert.Equal(t, "pkg-list@0.0.1", list[2].Package)
+    assert.Equal(t, "include/pkg-list.h", list[2].File)
+    assert.Equal(t, "pkg-list@0.0.1 include/pkg-list.h:3:1", list[2].Location())
+
+    assert.Equal(t, 0, list[3].Column)
+    assert.Equal(t, "src/main.cpp:20", list[3].Location())
+}
+
+func TestSet_ParseDeduplicates(t *testing.T) {
+    set := NewSet("/home/dev/app")
+    set.Parse("main", buildOutput)
+    set.Parse("main", buildOutput)
+    set.Parse("tests", buildOutput)
+
+    list := set.List()
+    assert.Equal(t, 4, len(list))
+    for _, diagnostic := range list {
+        assert.Equal(t, []string{"main", "tests"}, diagnostic.Targets)
+    }
+}
+
+func TestSet_WriteJson(t *testing.T) {
+    buf := &bytes.Buffer{}
+    assert.Nil(t, NewSet("").WriteJson(buf))
+    assert.Equal(t, "[]\n", buf.String())
+
+    set := NewSet("/home/dev/app")
+    set.Parse("main", buildOutput)
+    buf.Reset()
+    assert.Nil(t, set.WriteJson(buf))
+
+    var decoded []Diagnostic
+    assert.Nil(t, json.Unmarshal(buf.Bytes(), &decoded))
+    assert.Equal(t, 4, len(decoded))
+    assert.Equal(t, "pkg-list@0.0.1", decoded[2].Package)
+}
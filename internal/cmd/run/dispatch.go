@@ -1,10 +1,12 @@
 package run
 
 import (
-    "fmt"
+    "os"
     "strings"
+    "wio/internal/cmd"
     "wio/internal/cmd/run/cmake"
     "wio/internal/cmd/run/dependencies"
+    "wio/internal/cmd/run/tests"
     "wio/internal/constants"
     "wio/internal/types"
     "wio/pkg/log"
@@ -21,8 +23,9 @@ var dispatchCmakeFuncPlatform = map[string]dispatchCmakeFunc{
     constants.Native: dispatchCmakeNative,
 }
 var dispatchCmakeFuncAvrFramework = map[string]dispatchCmakeFunc{
-    constants.Cosa:    dispatchCmakeAvrCosa,
-    constants.Arduino: dispatchCmakeAvrArduino,
+    constants.Cosa:      dispatchCmakeAvrCosa,
+    constants.Arduino:   dispatchCmakeAvrArduino,
+    constants.Baremetal: dispatchCmakeAvrBaremetal,
 }
 
 func dispatchCmake(info *runInfo, target types.Target) error {
@@ -30,13 +33,11 @@ func dispatchCmake(info *runInfo, target types.Target) error {
 
     // this means platform was not specified at all
     if strings.Trim(platform, " ") == "" {
-        message := fmt.Sprintf("No Platform specified by the [%s] target", target.GetName())
-        return util.Error(message)
+        return util.Error("No Platform specified by the [%s] target", target.GetName())
     }
 
     if _, exists := dispatchCmakeFuncPlatform[platform]; !exists {
-        message := fmt.Sprintf("Platform [%s] is not supported", platform)
-        return util.Error(message)
+        return util.Error("Platform [%s] is not supported", platform)
     }
     return dispatchCmakeFuncPlatform[platform](info, target)
 }
@@ -47,20 +48,17 @@ func dispatchCmakeAvr(info *runInfo, target types.Target) error {
 
     // this means framework was not specified at all
     if framework == "" {
-        message := fmt.Sprintf("No Framework specified by the [%s] target. Try one of %s",
+        return util.Error("No Framework specified by the [%s] target. Try one of %s",
             target.GetName(), funk.Keys(dispatchCmakeFuncAvrFramework))
-        return util.Error(message)
     }
 
     // this means board was not specified at all
     if board == "" {
-        message := fmt.Sprintf("No Board specified by the [%s] target", target.GetName())
-        return util.Error(message)
+        return util.Error("No Board specified by the [%s] target", target.GetName())
     }
 
     if _, exists := dispatchCmakeFuncAvrFramework[framework]; !exists {
-        message := fmt.Sprintf("Framework [%s] not supported", framework)
-        return util.Error(message)
+        return util.Error("Framework [%s] not supported", framework)
     }
     return dispatchCmakeFuncAvrFramework[framework](info, target)
 }
@@ -72,34 +70,43 @@ func dispatchCmakeNative(info *runInfo, target types.Target) error {
 func dispatchCmakeAvrCosa(info *runInfo, target types.Target) error {
     projectName := info.config.GetName()
     projectPath := info.directory
-    port, err := getPort(info)
-    if err != nil && info.runType == TypeRun {
-        return err
-    }
     cppStandard, cStandard, err := cmake.GetStandard(info.config.GetInfo().GetOptions().GetStandard())
     if err != nil {
         return err
     }
 
     return cmake.GenerateAvrCmakeLists("toolchain/cmake/CosaToolchain.cmake", target,
-        projectName, projectPath, cppStandard, cStandard, port)
+        targetPath(info, target), info.profile, projectName, projectPath, cppStandard, cStandard)
 }
 
 func dispatchCmakeAvrArduino(info *runInfo, target types.Target) error {
     projectName := info.config.GetName()
     projectPath := info.directory
-    port, err := getPort(info)
-    if err != nil && info.runType == TypeRun {
+
+    cppStandard, cStandard, err := cmake.GetStandard(info.config.GetInfo().GetOptions().GetStandard())
+    if err != nil {
         return err
     }
 
+    return cmake.GenerateAvrCmakeLists("toolchain/cmake/ArduinoToolchain.cmake", target,
+        targetPath(info, target), info.profile, projectName, projectPath, cppStandard, cStandard)
+}
+
+func dispatchCmakeAvrBaremetal(info *runInfo, target types.Target) error {
+    projectName := info.config.GetName()
+    projectPath := info.directory
+
     cppStandard, cStandard, err := cmake.GetStandard(info.config.GetInfo().GetOptions().GetStandard())
     if err != nil {
         return err
     }
+    board, err := avrBoard(target)
+    if err != nil {
+        return err
+    }
 
-    return cmake.GenerateAvrCmakeLists("toolchain/cmake/ArduinoToolchain.cmake", target,
-        projectName, projectPath, cppStandard, cStandard, port)
+    return cmake.GenerateAvrBaremetalCmakeLists(target, targetPath(info, target), info.profile, projectName,
+        projectPath, cppStandard, cStandard, board.Mcu, board.FCpu)
 }
 
 func dispatchCmakeNativeGeneric(info *runInfo, target types.Target) error {
@@ -111,18 +118,24 @@ func dispatchCmakeNativeGeneric(info *runInfo, target types.Target) error {
         return err
     }
 
-    return cmake.GenerateNativeCmakeLists(target, projectName, projectPath, cppStandard, cStandard)
+    var compileFlags, linkFlags []string
+    if info.variant != nil {
+        compileFlags = info.variant.compileFlags
+        linkFlags = info.variant.linkFlags
+    }
+    return cmake.GenerateNativeCmakeLists(target, targetPath(info, target), info.profile, projectName,
+        projectPath, cppStandard, cStandard, compileFlags, linkFlags)
 }
 
 func dispatchCmakeDependencies(info *runInfo, target types.Target) error {
-    cmakePath := sys.Path(cmake.BuildPath(info.directory), target.GetName())
-    cmakePath = sys.Path(cmakePath, "dependencies.cmake")
+    cmakePath := sys.Path(targetPath(info, target), "dependencies.cmake")
 
     buildTargets, err := dependencies.CreateBuildTargets(info.directory, target)
     if err != nil {
         return err
     } else {
-        err := dependencies.GenerateCMakeDependencies(cmakePath, target.GetPlatform(), buildTargets)
+        err := dependencies.GenerateCMakeDependencies(cmakePath, target.GetPlatform(), target.GetFramework(),
+            buildTargets)
         if err != nil {
             return err
         }
@@ -137,12 +150,14 @@ func dispatchRunTarget(info *runInfo, target types.Target) error {
     platform := target.GetPlatform()
     switch platform {
     case constants.Avr:
-        var err error = nil
-        err = portReconfigure(info, target)
-        if err == nil {
-            err = uploadTarget(binDir)
+        if info.context.Bool("simulate") {
+            exitCode, err := simulateTarget(info, target, os.Stdout)
+            if err == nil && exitCode != 0 {
+                err = cmd.NewExitError(exitCode, util.Error("firmware exited with code %d", exitCode))
+            }
+            return err
         }
-        return err
+        return uploadFirmware(info, target)
     case constants.Native:
         args := info.context.String("args")
         return runTarget(info.directory, sys.Path(binDir, target.GetName()), args)
@@ -151,6 +166,25 @@ func dispatchRunTarget(info *runInfo, target types.Target) error {
     }
 }
 
+func dispatchTestTarget(info *runInfo, target types.Target, index int, suite *tests.Suite) error {
+    platform := target.GetPlatform()
+    switch platform {
+    case constants.Avr:
+        if info.context.Bool("simulate") {
+            return runSimulatedTest(info, target, index, suite)
+        }
+        if !info.context.IsSet("port") && target.GetUpload().GetPort() == "" {
+            suite.SkipReason = "AVR tests need a device to run on, use --port, the upload port in wio.yml or --simulate"
+            return nil
+        }
+        return runDeviceTest(info, target, index, suite)
+    case constants.Native:
+        return runNativeTest(info, target, index, suite)
+    default:
+        return util.Error("Platform [%s] is not supported", platform)
+    }
+}
+
 func dispatchCanRunTarget(info *runInfo, target types.Target) bool {
     binDir := binaryPath(info, target)
     platform := target.GetPlatform()
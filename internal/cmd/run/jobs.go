@@ -0,0 +1,138 @@
+package run
+
+import (
+    "path/filepath"
+    "runtime"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+    "wio/pkg/log"
+    "wio/pkg/util"
+)
+
+// Result of applying a target function to a single target directory
+type targetResult struct {
+    dir      string
+    err      error
+    skipped  bool
+    duration time.Duration
+    output   string
+}
+
+// Scheduler shares one bounded job budget between all the targets being processed.
+// At most workers targets run at once and each of them gets an equal share of the
+// jobs, so the total number of compiler processes never exceeds the budget.
+// With failFast set, targets that have not started yet are skipped after the first failure
+type scheduler struct {
+    jobs     int
+    workers  int
+    failFast bool
+    output   outputOptions
+    failed   int32
+}
+
+func defaultJobs() int {
+    return runtime.NumCPU() + 2
+}
+
+func newScheduler(jobs int, numTargets int, failFast bool, output outputOptions) *scheduler {
+    if jobs <= 0 {
+        jobs = defaultJobs()
+    }
+    workers := numTargets
+    if workers > jobs {
+        workers = jobs
+    }
+    if workers <= 0 {
+        workers = 1
+    }
+    return &scheduler{jobs: jobs, workers: workers, failFast: failFast, output: output}
+}
+
+// number of jobs the target run by a worker is allowed to use. The remainder of the
+// budget is spread over the first workers so that none of it goes unused
+func (s *scheduler) targetJobs(worker int) int {
+    jobs := s.jobs / s.workers
+    if worker < s.jobs%s.workers {
+        jobs++
+    }
+    return jobs
+}
+
+// Applies the function to every target directory and waits for all of them to finish.
+// Results are returned in the same order as the directories
+func (s *scheduler) apply(function targetFunc, targetDirs []string) []targetResult {
+    results := make([]targetResult, len(targetDirs))
+    queue := make(chan int)
+    wait := sync.WaitGroup{}
+
+    for i := 0; i < s.workers; i++ {
+        wait.Add(1)
+        go func(worker int) {
+            defer wait.Done()
+            for index := range queue {
+                results[index] = s.run(function, s.targetJobs(worker), index, targetDirs[index])
+            }
+        }(i)
+    }
+    for index := range targetDirs {
+        queue <- index
+    }
+    close(queue)
+    wait.Wait()
+    return results
+}
+
+func (s *scheduler) run(function targetFunc, jobs int, index int, dir string) targetResult {
+    if s.failFast && atomic.LoadInt32(&s.failed) != 0 {
+        return targetResult{dir: dir, skipped: true}
+    }
+    output, err := s.output.open(index, dir)
+    if err != nil {
+        return targetResult{dir: dir, err: err}
+    }
+
+    start := time.Now()
+    result := targetResult{dir: dir}
+    result.err = function(dir, jobs, output)
+    result.duration = time.Since(start)
+    if result.err != nil {
+        atomic.StoreInt32(&s.failed, 1)
+    }
+    if err := output.Close(resultStatus(result)); err != nil && result.err == nil {
+        result.err = err
+    }
+    result.output = output.String()
+    return result
+}
+
+// Reports success or failure of every target and returns an error if any of them failed
+func reportResults(results []targetResult) error {
+    for _, result := range results {
+        log.Info(log.Cyan, "Target %s ... ", filepath.Base(result.dir))
+        if result.skipped {
+            log.Writeln(log.INFO, log.Yellow, "skipped")
+        } else if result.err != nil {
+            log.WriteFailure()
+            log.Errln("%s", result.err)
+        } else {
+            log.WriteSuccess()
+        }
+    }
+    return resultsError(results)
+}
+
+// Combines the failures of all the targets into one error
+func resultsError(results []targetResult) error {
+    failed := make([]string, 0, len(results))
+    for _, result := range results {
+        if result.err != nil {
+            failed = append(failed, filepath.Base(result.dir))
+        }
+    }
+    if len(failed) > 0 {
+        return util.Error("%d of %d targets failed: %s", len(failed), len(results), strings.Join(failed, ", "))
+    }
+    return nil
+}
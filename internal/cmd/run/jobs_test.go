@@ -0,0 +1,94 @@
+package run
+
+import (
+    "errors"
+    "io"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestSchedulerJobs(t *testing.T) {
+    tests := []struct {
+        name       string
+        jobs       int
+        numTargets int
+        workers    int
+        targetJobs []int
+    }{
+        {"fewer jobs than targets", 2, 5, 2, []int{1, 1}},
+        {"more jobs than targets", 8, 2, 2, []int{4, 4}},
+        {"leftover jobs", 7, 3, 3, []int{3, 2, 2}},
+        {"single target", 4, 1, 1, []int{4}},
+        {"no targets", 4, 0, 1, []int{4}},
+    }
+    for _, test := range tests {
+        s := newScheduler(test.jobs, test.numTargets, false, outputOptions{})
+        assert.Equal(t, test.workers, s.workers, test.name)
+
+        targetJobs := make([]int, s.workers)
+        total := 0
+        for worker := range targetJobs {
+            targetJobs[worker] = s.targetJobs(worker)
+            total += targetJobs[worker]
+        }
+        assert.Equal(t, test.targetJobs, targetJobs, test.name)
+        assert.Equal(t, test.jobs, total, test.name)
+    }
+
+    s := newScheduler(0, 100, false, outputOptions{})
+    assert.Equal(t, defaultJobs(), s.jobs)
+}
+
+func TestSchedulerApplyOrder(t *testing.T) {
+    dirs := []string{"a", "b", "c", "d", "e", "f"}
+    delays := map[string]time.Duration{"a": 30, "b": 0, "c": 20, "d": 10, "e": 0, "f": 5}
+
+    lock := sync.Mutex{}
+    targetJobs := map[string]int{}
+    function := func(dir string, jobs int, output io.Writer) error {
+        time.Sleep(delays[dir] * time.Millisecond)
+        lock.Lock()
+        targetJobs[dir] = jobs
+        lock.Unlock()
+        return nil
+    }
+
+    results := newScheduler(4, len(dirs), false, outputOptions{}).apply(function, dirs)
+    assert.Equal(t, len(dirs), len(results))
+    for i, result := range results {
+        assert.Equal(t, dirs[i], result.dir)
+        assert.Nil(t, result.err)
+        assert.False(t, result.skipped)
+        assert.Equal(t, 1, targetJobs[dirs[i]])
+    }
+}
+
+func TestSchedulerFailFast(t *testing.T) {
+    dirs := []string{"a", "b", "c", "d"}
+    function := func(dir string, jobs int, output io.Writer) error {
+        if dir == "b" {
+            return errors.New("failed")
+        }
+        return nil
+    }
+
+    results := newScheduler(1, len(dirs), true, outputOptions{}).apply(function, dirs)
+    assert.Nil(t, results[0].err)
+    assert.False(t, results[0].skipped)
+    assert.NotNil(t, results[1].err)
+    assert.False(t, results[1].skipped)
+    for _, result := range results[2:] {
+        assert.Nil(t, result.err)
+        assert.True(t, result.skipped)
+    }
+    assert.Equal(t, "1 of 4 targets failed: b", resultsError(results).Error())
+
+    results = newScheduler(1, len(dirs), false, outputOptions{}).apply(function, dirs)
+    for _, result := range results {
+        assert.False(t, result.skipped)
+    }
+    assert.NotNil(t, results[1].err)
+}
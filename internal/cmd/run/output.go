@@ -0,0 +1,102 @@
+package run
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "sync"
+    "wio/pkg/log"
+    "wio/pkg/util/sys"
+
+    "github.com/fatih/color"
+)
+
+const buildLogFile = "build.log"
+
+var prefixColors = []*color.Color{log.Cyan, log.Magenta, log.Blue, log.Yellow, log.Green}
+
+// guards printing of grouped output so blocks of different targets do not mix
+var groupLock sync.Mutex
+
+// Options for what is done with the output of commands run for each target
+type outputOptions struct {
+    // hold the output back and print it as one block once the target is done
+    group bool
+    // also write the full output to .wio/targets/<name>/build.log
+    logFile bool
+}
+
+// Output of a single target. Everything written to it is captured in a buffer and
+// either streamed line by line with a [target] prefix or printed as one block when closed
+type targetOutput struct {
+    name    string
+    options outputOptions
+    buffer  bytes.Buffer
+    stream  *log.PrefixWriter
+    file    *os.File
+    writer  io.Writer
+}
+
+func buildLogPath(dir string) string {
+    return sys.Path(dir, buildLogFile)
+}
+
+func (options outputOptions) open(index int, dir string) (*targetOutput, error) {
+    output := &targetOutput{
+        name:    filepath.Base(dir),
+        options: options,
+    }
+    writers := []io.Writer{&output.buffer}
+
+    if !options.group {
+        prefixColor := prefixColors[index%len(prefixColors)]
+        output.stream = log.NewPrefixWriter(fmt.Sprintf("[%s] ", output.name), prefixColor, log.INFO)
+        writers = append(writers, output.stream)
+    }
+
+    if options.logFile {
+        if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+            return nil, err
+        }
+        file, err := os.Create(buildLogPath(dir))
+        if err != nil {
+            return nil, err
+        }
+        output.file = file
+        writers = append(writers, file)
+    }
+
+    output.writer = io.MultiWriter(writers...)
+    return output, nil
+}
+
+func (output *targetOutput) Write(p []byte) (int, error) {
+    return output.writer.Write(p)
+}
+
+// Flushes the output and, when grouping, prints it under a header with the target status
+func (output *targetOutput) Close(status string) error {
+    if output.stream != nil {
+        output.stream.Flush()
+    }
+    if output.options.group {
+        groupLock.Lock()
+        log.Infoln(statusColors[status], "===== %s (%s) =====", output.name, status)
+        log.Info("%s", output.buffer.String())
+        if output.buffer.Len() > 0 && output.buffer.Bytes()[output.buffer.Len()-1] != '\n' {
+            log.Infoln()
+        }
+        groupLock.Unlock()
+    }
+    if output.file != nil {
+        return output.file.Close()
+    }
+    return nil
+}
+
+// Captured output of the target
+func (output *targetOutput) String() string {
+    return output.buffer.String()
+}
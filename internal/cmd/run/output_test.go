@@ -0,0 +1,70 @@
+package run
+
+import (
+    "io"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestTargetOutputStream(t *testing.T) {
+    var output *targetOutput
+    out, _ := captureLogs(func() {
+        var err error
+        output, err = outputOptions{}.open(0, filepath.Join("targets", "blink"))
+        assert.Nil(t, err)
+        io.WriteString(output, "line 1\nline")
+        io.WriteString(output, " 2\nno newline")
+        assert.Nil(t, output.Close(statusSuccess))
+    })
+    assert.Equal(t, "[blink] line 1\n[blink] line 2\n[blink] no newline\n", out)
+    assert.Equal(t, "line 1\nline 2\nno newline", output.String())
+}
+
+func TestTargetOutputGroup(t *testing.T) {
+    dirs := []string{filepath.Join("targets", "slow"), filepath.Join("targets", "fast")}
+    function := func(dir string, jobs int, output io.Writer) error {
+        name := filepath.Base(dir)
+        for i := 0; i < 3; i++ {
+            io.WriteString(output, name+"\n")
+            if name == "slow" {
+                time.Sleep(10 * time.Millisecond)
+            }
+        }
+        if name == "slow" {
+            io.WriteString(output, "done")
+        }
+        return nil
+    }
+
+    out, _ := captureLogs(func() {
+        newScheduler(2, len(dirs), false, outputOptions{group: true}).apply(function, dirs)
+    })
+    expected := "===== fast (success) =====\nfast\nfast\nfast\n" +
+        "===== slow (success) =====\nslow\nslow\nslow\ndone\n"
+    assert.Equal(t, expected, out)
+}
+
+func TestTargetOutputLogFile(t *testing.T) {
+    dir, err := ioutil.TempDir("", "wio-output")
+    assert.Nil(t, err)
+    defer os.RemoveAll(dir)
+
+    targetDir := filepath.Join(dir, "blink")
+    out, _ := captureLogs(func() {
+        output, err := outputOptions{group: true, logFile: true}.open(0, targetDir)
+        assert.Nil(t, err)
+        io.WriteString(output, "compiling\nlinking\n")
+        assert.Nil(t, output.Close(statusFailure))
+    })
+    assert.True(t, strings.HasSuffix(out, "compiling\nlinking\n"))
+
+    data, err := ioutil.ReadFile(buildLogPath(targetDir))
+    assert.Nil(t, err)
+    assert.Equal(t, "compiling\nlinking\n", string(data))
+}
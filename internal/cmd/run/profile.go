@@ -0,0 +1,75 @@
+package run
+
+import (
+    "sort"
+    "strings"
+    "wio/internal/types"
+    "wio/pkg/util"
+)
+
+// Profiles every project has. Profiles with the same name in wio.yml extend these. An
+// optimisation level in the flags replaces the one of the toolchain, e.g. -Os on AVR
+var builtinProfiles = map[string]*types.ProfileImpl{
+    "debug":   {BuildType: "Debug", Flags: []string{"-O0", "-g"}},
+    "release": {BuildType: "Release"},
+    "minsize": {BuildType: "MinSizeRel", Flags: []string{"-Os"}},
+}
+
+var buildTypes = []string{"Debug", "Release", "MinSizeRel", "RelWithDebInfo"}
+
+// Matches the build type given in a profile to the name CMake expects
+func getBuildType(buildType string) (string, error) {
+    if buildType == "" {
+        return "", nil
+    }
+    for _, name := range buildTypes {
+        if strings.EqualFold(name, buildType) {
+            return name, nil
+        }
+    }
+    return "", util.Error("invalid build type %s, supported are: %s", buildType, strings.Join(buildTypes, ", "))
+}
+
+// Resolves the profile with the given name from the built-in profiles and the ones
+// defined in the project config. Returns nil if no profile is given
+func getProfile(config types.Config, name string) (types.Profile, error) {
+    if name == "" {
+        return nil, nil
+    }
+    builtin, isBuiltin := builtinProfiles[name]
+    custom, isCustom := config.GetProfiles()[name]
+    if !isBuiltin && !isCustom {
+        available := make([]string, 0, len(builtinProfiles))
+        for profileName := range builtinProfiles {
+            available = append(available, profileName)
+        }
+        for profileName := range config.GetProfiles() {
+            if _, exists := builtinProfiles[profileName]; !exists {
+                available = append(available, profileName)
+            }
+        }
+        sort.Strings(available)
+        return nil, util.Error("profile %s does not exist, available are: %s", name, strings.Join(available, ", "))
+    }
+
+    profile := &types.ProfileImpl{}
+    if isBuiltin {
+        profile.BuildType = builtin.GetBuildType()
+        profile.Flags = append(profile.Flags, builtin.GetFlags()...)
+        profile.Definitions = append(profile.Definitions, builtin.GetDefinitions()...)
+    }
+    if isCustom {
+        if custom.GetBuildType() != "" {
+            profile.BuildType = custom.GetBuildType()
+        }
+        profile.Flags = append(profile.Flags, custom.GetFlags()...)
+        profile.Definitions = append(profile.Definitions, custom.GetDefinitions()...)
+    }
+
+    buildType, err := getBuildType(profile.BuildType)
+    if err != nil {
+        return nil, util.Error("profile %s: %s", name, err.Error())
+    }
+    profile.BuildType = buildType
+    return profile, nil
+}
@@ -0,0 +1,58 @@
+package run
+
+import (
+    "testing"
+    "wio/internal/types"
+    "wio/pkg/util/sys"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestGetProfile(t *testing.T) {
+    config := &types.ConfigImpl{
+        Profiles: map[string]*types.ProfileImpl{
+            "release":  {Flags: []string{"-flto"}},
+            "profiled": {BuildType: "relwithdebinfo", Flags: []string{"-pg"}, Definitions: []string{"PROFILED"}},
+            "broken":   {BuildType: "Fast"},
+        },
+    }
+
+    profile, err := getProfile(config, "")
+    assert.Nil(t, err)
+    assert.Nil(t, profile)
+
+    profile, err = getProfile(config, "debug")
+    assert.Nil(t, err)
+    assert.Equal(t, "Debug", profile.GetBuildType())
+    assert.Equal(t, []string{"-O0", "-g"}, profile.GetFlags())
+
+    profile, err = getProfile(config, "release")
+    assert.Nil(t, err)
+    assert.Equal(t, "Release", profile.GetBuildType())
+    assert.Equal(t, []string{"-flto"}, profile.GetFlags())
+
+    profile, err = getProfile(config, "profiled")
+    assert.Nil(t, err)
+    assert.Equal(t, "RelWithDebInfo", profile.GetBuildType())
+    assert.Equal(t, []string{"PROFILED"}, profile.GetDefinitions())
+
+    _, err = getProfile(config, "broken")
+    assert.NotNil(t, err)
+
+    _, err = getProfile(config, "missing")
+    assert.NotNil(t, err)
+}
+
+func TestProfileTargetPath(t *testing.T) {
+    profile, err := getProfile(&types.ConfigImpl{}, "release")
+    assert.Nil(t, err)
+    plain := &runInfo{directory: "project"}
+    profiled := &runInfo{directory: "project", profileName: "release", profile: profile}
+
+    app := newTarget("app", "native", "")
+    appRelease := newTarget("app-release", "native", "")
+    assert.Equal(t, sys.Path(buildPath(plain), "app"), targetPath(plain, app))
+    assert.Equal(t, sys.Path(buildPath(plain), "app@release"), targetPath(profiled, app))
+    assert.NotEqual(t, targetPath(profiled, app), targetPath(plain, appRelease))
+    assert.NotEqual(t, targetPath(profiled, app), targetPath(profiled, appRelease))
+}
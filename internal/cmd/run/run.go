@@ -8,7 +8,8 @@ package run
 
 import (
     "os"
-    "runtime"
+    "sort"
+    "wio/internal/constants"
     "wio/internal/types"
     "wio/pkg/log"
     "wio/pkg/util"
@@ -25,9 +26,14 @@ type Run struct {
 }
 
 const (
-    TypeBuild Type = 0
-    TypeClean Type = 1
-    TypeRun   Type = 2
+    TypeBuild          Type = 0
+    TypeClean          Type = 1
+    TypeRun            Type = 2
+    TypeCompdb         Type = 3
+    TypeTest           Type = 4
+    TypeSize           Type = 5
+    TypeUpload         Type = 6
+    TypeBurnBootloader Type = 7
 )
 
 type runInfo struct {
@@ -41,6 +47,10 @@ type runInfo struct {
 
     runType Type
     jobs    int
+    variant *buildVariant
+
+    profileName string
+    profile     types.Profile
 }
 
 type runExecuteFunc func(*runInfo, []types.Target) error
@@ -49,6 +59,11 @@ var runFuncs = []runExecuteFunc{
     (*runInfo).build,
     (*runInfo).clean,
     (*runInfo).run,
+    (*runInfo).compdb,
+    (*runInfo).test,
+    (*runInfo).size,
+    (*runInfo).upload,
+    (*runInfo).burnBootloader,
 }
 
 // get context for the command
@@ -74,6 +89,17 @@ func (run Run) Execute() error {
         projectType: config.GetType(),
         headerOnly:  config.GetInfo().GetOptions().GetIsHeaderOnly(),
         targets:     targets,
+        jobs:        run.Context.Int("jobs"),
+        profileName: run.Context.String("profile"),
+    }
+    if info.profile, err = getProfile(config, info.profileName); err != nil {
+        return err
+    }
+    if info.variant, err = getVariant(run.Context); err != nil {
+        return err
+    }
+    if info.jobs <= 0 {
+        info.jobs = defaultJobs()
     }
     if err := info.execute(run.RunType); err != nil {
         return err
@@ -92,6 +118,23 @@ func (info *runInfo) execute(runType Type) error {
     }
     log.WriteSuccess()
 
+    if info.runType == TypeBuild && info.context.IsSet("port") {
+        log.Warnln("--port is deprecated for build, the port is only used when uploading")
+    }
+    if info.profile != nil {
+        log.Info(log.Cyan, "Profile: ")
+        log.Infoln(log.Magenta, info.profileName)
+    }
+    if info.variant != nil {
+        log.Info(log.Cyan, "Variant: ")
+        log.Infoln(log.Magenta, info.variant.name)
+        for _, target := range targets {
+            if target.GetPlatform() != constants.Native {
+                log.Warnln("variants only apply to native targets, %s is built normally", target.GetName())
+            }
+        }
+    }
+
     return runFuncs[info.runType](info, targets)
 }
 
@@ -102,9 +145,9 @@ func (info *runInfo) clean(targets []types.Target) error {
     }
 
     log.Infoln(log.Cyan.Add(color.Underline), "Cleaning targets")
-    log.Infoln(log.Magenta, "Running with JOBS=%d", runtime.NumCPU()+2)
-    errs := asyncCleanTargets(targetDirs, info.context.Bool("hard"))
-    if err := awaitErrors(errs); err != nil {
+    log.Infoln(log.Magenta, "Running with JOBS=%d", info.jobs)
+    results := asyncCleanTargets(targetDirs, info.jobs, info.context.Bool("hard"))
+    if err := reportResults(results); err != nil {
         return err
     }
     log.Infoln(log.Green, "Done!")
@@ -112,6 +155,9 @@ func (info *runInfo) clean(targets []types.Target) error {
 }
 
 func (info *runInfo) build(targets []types.Target) error {
+    if err := checkDiagnosticsFlags(info); err != nil {
+        return err
+    }
     log.Infoln(log.Cyan, "Generating files ... ")
     targetDirs, err := configureTargets(info, targets)
     if err != nil {
@@ -119,9 +165,21 @@ func (info *runInfo) build(targets []types.Target) error {
     }
 
     log.Infoln(log.Cyan.Add(color.Underline), "Building targets")
-    log.Infoln(log.Magenta, "Running with JOBS=%d", runtime.NumCPU()+2)
-    errs := asyncBuildTargets(targetDirs)
-    return awaitErrors(errs)
+    log.Infoln(log.Magenta, "Running with JOBS=%d", info.jobs)
+    output := outputOptions{
+        group:   info.context.Bool("group-output"),
+        logFile: info.context.Bool("build-log"),
+    }
+    results := asyncBuildTargets(targetDirs, info.jobs, info.context.Bool("fail-fast"), output)
+    printSummary(info, targets, results)
+    sizeErr := reportSizes(info, targets, results)
+    if err := reportDiagnostics(info, targets, results); err != nil {
+        return err
+    }
+    if err := resultsError(results); err != nil {
+        return err
+    }
+    return sizeErr
 }
 
 func (info *runInfo) run(targets []types.Target) error {
@@ -133,7 +191,16 @@ func (info *runInfo) run(targets []types.Target) error {
             return err
         }
     }
-    return dispatchRunTarget(info, target)
+    if err := dispatchRunTarget(info, target); err != nil {
+        return err
+    }
+    if info.context.Bool("monitor") {
+        if target.GetPlatform() != constants.Avr || info.context.Bool("simulate") {
+            return util.Error("--monitor needs an AVR target running on a device")
+        }
+        return monitorTarget(info, target)
+    }
+    return nil
 }
 
 func getTargetArgs(info *runInfo) ([]types.Target, error) {
@@ -145,6 +212,9 @@ func getTargetArgs(info *runInfo) ([]types.Target, error) {
             target.SetName(name)
             targets = append(targets, target)
         }
+        sort.Slice(targets, func(i, j int) bool {
+            return targets[i].GetName() < targets[j].GetName()
+        })
     } else {
         for _, name := range info.targets {
             if _, exists := projectTargets[name]; exists {
@@ -154,7 +224,20 @@ func getTargetArgs(info *runInfo) ([]types.Target, error) {
                 return nil, util.Error("unrecognized target %s", name)
             }
         }
-        if len(info.targets) <= 0 {
+        if len(info.targets) <= 0 && info.runType == TypeTest {
+            for name, target := range projectTargets {
+                if target.IsTest() {
+                    target.SetName(name)
+                    targets = append(targets, target)
+                }
+            }
+            if len(targets) <= 0 {
+                return nil, util.Error("no test targets specified, mark targets with test: true")
+            }
+            sort.Slice(targets, func(i, j int) bool {
+                return targets[i].GetName() < targets[j].GetName()
+            })
+        } else if len(info.targets) <= 0 {
             defaultName := info.config.GetInfo().GetOptions().GetDefault()
             if defaultName == "" {
                 return nil, util.Error("no default target specified")
@@ -187,34 +270,15 @@ func configureTargets(info *runInfo, targets []types.Target) ([]string, error) {
     return targetDirs, nil
 }
 
-func asyncBuildTargets(targetDirs []string) []chan error {
+func asyncBuildTargets(targetDirs []string, jobs int, failFast bool, output outputOptions) []targetResult {
     var function targetFunc = configAndBuild
-    return function.asyncApply(targetDirs)
+    return newScheduler(jobs, len(targetDirs), failFast, output).apply(function, targetDirs)
 }
 
-func asyncCleanTargets(targetDirs []string, hard bool) []chan error {
+func asyncCleanTargets(targetDirs []string, jobs int, hard bool) []targetResult {
     var function targetFunc = cleanIfExists
     if hard {
         function = hardClean
     }
-    return function.asyncApply(targetDirs)
-}
-
-func (function targetFunc) asyncApply(targetDirs []string) []chan error {
-    errs := make([]chan error, 0, len(targetDirs))
-    for _, targetDir := range targetDirs {
-        err := make(chan error)
-        go function(targetDir, err)
-        errs = append(errs, err)
-    }
-    return errs
-}
-
-func awaitErrors(errs []chan error) error {
-    for _, errChan := range errs {
-        if err := <-errChan; err != nil {
-            return err
-        }
-    }
-    return nil
+    return newScheduler(jobs, len(targetDirs), false, outputOptions{}).apply(function, targetDirs)
 }
@@ -0,0 +1,109 @@
+package run
+
+import (
+    "bufio"
+    "context"
+    "io"
+    "io/ioutil"
+    "os/exec"
+    "regexp"
+    "strconv"
+    "strings"
+    "syscall"
+    "time"
+    "wio/internal/types"
+    "wio/pkg/log"
+    "wio/pkg/util"
+    "wio/pkg/util/sys"
+)
+
+// Firmware signals its exit code to the simulator by printing this line over UART
+var simulatorExitPattern = regexp.MustCompile(`^WIO_EXIT (\d+)\s*$`)
+
+// simavr may color the UART output it prints
+var escapePattern = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// Runs the target firmware in simavr with the MCU and clock of its board and returns the
+// exit code it signals. The UART output of the firmware is copied to out
+func simulateTarget(info *runInfo, target types.Target, out io.Writer) (int, error) {
+    board, err := avrBoard(target)
+    if err != nil {
+        return -1, err
+    }
+    elf := sys.Path(binaryPath(info, target), target.GetName()+platformExtension(target.GetPlatform()))
+    args := []string{"-m", board.Mcu, "-f", strconv.Itoa(board.FCpu), elf}
+    log.Verbln(log.Magenta, "Simulating %s on %s with simavr %s", target.GetName(), board.Name,
+        strings.Join(args, " "))
+    return runSimulator(info.directory, "simavr", args, info.context.Duration("timeout"), out)
+}
+
+// Copies the lines of the simulator output without their colors to out. The line the firmware
+// signals its exit code with is not copied, the code is passed to exited instead
+func scanSimulatorOutput(reader io.Reader, out io.Writer, exited func(int)) {
+    scanner := bufio.NewScanner(reader)
+    for scanner.Scan() {
+        line := strings.TrimRight(escapePattern.ReplaceAllString(scanner.Text(), ""), "\r")
+        if match := simulatorExitPattern.FindStringSubmatch(line); match != nil {
+            exitCode, _ := strconv.Atoi(match[1])
+            exited(exitCode)
+            continue
+        }
+        io.WriteString(out, line+"\n")
+    }
+    io.Copy(ioutil.Discard, reader)
+}
+
+// Runs the simulator and returns the exit code the firmware signals, or the one of the
+// simulator if the firmware stops it another way. A timeout of 0 lets it run until it exits
+func runSimulator(dir string, program string, args []string, timeout time.Duration, out io.Writer) (int, error) {
+    var ctx context.Context
+    var cancel context.CancelFunc
+    if timeout > 0 {
+        ctx, cancel = context.WithTimeout(context.Background(), timeout)
+    } else {
+        ctx, cancel = context.WithCancel(context.Background())
+    }
+    defer cancel()
+
+    cmd := exec.CommandContext(ctx, program, args...)
+    cmd.Dir = dir
+    reader, writer := io.Pipe()
+    cmd.Stdout = writer
+    cmd.Stderr = writer
+    if err := cmd.Start(); err != nil {
+        return -1, err
+    }
+
+    exitCode := 0
+    signaled := false
+    scanned := make(chan struct{})
+    go func() {
+        defer close(scanned)
+        scanSimulatorOutput(reader, out, func(code int) {
+            exitCode = code
+            signaled = true
+            cancel()
+        })
+    }()
+
+    err := cmd.Wait()
+    writer.Close()
+    <-scanned
+
+    switch {
+    case signaled:
+        // firmware signaled its exit code and the simulator was stopped
+        return exitCode, nil
+    case ctx.Err() == context.DeadlineExceeded:
+        return -1, util.Error("simulation timed out after %s", timeout)
+    case err != nil:
+        if exitErr, ok := err.(*exec.ExitError); ok {
+            if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+                return status.ExitStatus(), nil
+            }
+        }
+        return -1, err
+    default:
+        return 0, nil
+    }
+}
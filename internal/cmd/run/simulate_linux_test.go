@@ -0,0 +1,44 @@
+package run
+
+import (
+    "bytes"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+// Runs a shell script in place of simavr. Scripts that keep running exec into the command so
+// that, like simavr, there is only one process to stop
+func runFakeSimulator(script string, timeout time.Duration) (int, string, error) {
+    out := &bytes.Buffer{}
+    exitCode, err := runSimulator("", "sh", []string{"-c", script}, timeout, out)
+    return exitCode, out.String(), err
+}
+
+func TestRunSimulator(t *testing.T) {
+    exitCode, out, err := runFakeSimulator("echo 'ok 1'; echo 'WIO_EXIT 2'; exec sleep 10", 0)
+    assert.Nil(t, err)
+    assert.Equal(t, 2, exitCode)
+    assert.Equal(t, "ok 1\n", out)
+
+    exitCode, out, err = runFakeSimulator("echo done", time.Minute)
+    assert.Nil(t, err)
+    assert.Equal(t, 0, exitCode)
+    assert.Equal(t, "done\n", out)
+
+    exitCode, out, err = runFakeSimulator("echo 'avr_gdb_init' >&2; exit 5", 0)
+    assert.Nil(t, err)
+    assert.Equal(t, 5, exitCode)
+    assert.Equal(t, "avr_gdb_init\n", out)
+
+    start := time.Now()
+    exitCode, out, err = runFakeSimulator("echo running; exec sleep 10", 100*time.Millisecond)
+    assert.NotNil(t, err)
+    assert.Equal(t, -1, exitCode)
+    assert.Equal(t, "running\n", out)
+    assert.True(t, time.Since(start) < 5*time.Second)
+
+    _, err = runSimulator("", "wio-missing-simavr", nil, 0, &bytes.Buffer{})
+    assert.NotNil(t, err)
+}
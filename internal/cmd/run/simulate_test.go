@@ -0,0 +1,34 @@
+package run
+
+import (
+    "bytes"
+    "strings"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestScanSimulatorOutput(t *testing.T) {
+    tests := []struct {
+        name     string
+        input    string
+        output   string
+        exitCode []int
+    }{
+        {"plain", "hello\nworld\n", "hello\nworld\n", nil},
+        {"colors", "\x1b[32mok 1 - blink\x1b[0m\r\n", "ok 1 - blink\n", nil},
+        {"exit", "1..1\nok 1\nWIO_EXIT 3\n", "1..1\nok 1\n", []int{3}},
+        {"colored exit", "\x1b[32mWIO_EXIT 0 \x1b[0m\r\nafter\n", "after\n", []int{0}},
+        {"not an exit", "WIO_EXIT\nWIO_EXIT 1 2\nsay WIO_EXIT 1\n", "WIO_EXIT\nWIO_EXIT 1 2\nsay WIO_EXIT 1\n", nil},
+        {"no newline", "last", "last\n", nil},
+    }
+    for _, test := range tests {
+        out := &bytes.Buffer{}
+        var exitCode []int
+        scanSimulatorOutput(strings.NewReader(test.input), out, func(code int) {
+            exitCode = append(exitCode, code)
+        })
+        assert.Equal(t, test.output, out.String(), test.name)
+        assert.Equal(t, test.exitCode, exitCode, test.name)
+    }
+}
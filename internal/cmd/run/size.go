@@ -0,0 +1,371 @@
+package run
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "text/tabwriter"
+    "wio/internal/cmd/run/dependencies"
+    "wio/internal/cmd/run/size"
+    "wio/internal/constants"
+    "wio/internal/toolchain"
+    "wio/internal/types"
+    "wio/pkg/log"
+    "wio/pkg/util"
+    "wio/pkg/util/sys"
+)
+
+// Memory used by one library linked into the firmware
+type librarySize struct {
+    name     string
+    sections size.Sections
+}
+
+// Reads the memory used by the firmware of an AVR target and by each library it is linked
+// from. Objects of the target itself are grouped under the name of the target
+func readTargetSize(info *runInfo, target types.Target) (size.Sections, []librarySize, error) {
+    binDir := binaryPath(info, target)
+    total, err := size.ReadElf(sys.Path(binDir, target.GetName()+platformExtension(target.GetPlatform())))
+    if err != nil {
+        return size.Sections{}, nil, err
+    }
+
+    targetObjects := sys.Path(binDir, "CMakeFiles", target.GetName()+".dir") + string(filepath.Separator)
+    targetLibrary := librarySize{name: target.GetName()}
+    libraries := make([]librarySize, 0, 8)
+    err = filepath.Walk(binDir, func(path string, fileInfo os.FileInfo, err error) error {
+        if err != nil || fileInfo.IsDir() {
+            return err
+        }
+        switch {
+        case filepath.Ext(path) == ".a":
+            sections, err := size.ReadArchive(path)
+            if err != nil {
+                return err
+            }
+            name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "lib"), ".a")
+            libraries = append(libraries, librarySize{name: name, sections: sections})
+        case filepath.Ext(path) == ".o" && strings.HasPrefix(path, targetObjects):
+            sections, err := size.ReadElf(path)
+            if err != nil {
+                return err
+            }
+            targetLibrary.sections = targetLibrary.sections.Add(sections)
+        }
+        return nil
+    })
+    if err != nil {
+        return size.Sections{}, nil, err
+    }
+
+    libraries = append(libraries, targetLibrary)
+    sort.SliceStable(libraries, func(i, j int) bool {
+        return libraries[i].sections.Flash() > libraries[j].sections.Flash()
+    })
+    return total, libraries, nil
+}
+
+func percentOf(used int64, available int64) string {
+    if available <= 0 {
+        return "-"
+    }
+    return fmt.Sprintf("%.1f%%", float64(used)/float64(available)*100)
+}
+
+func formatUsage(used int64, available int64) string {
+    if available <= 0 {
+        return fmt.Sprintf("%d B", used)
+    }
+    return fmt.Sprintf("%d / %d B", used, available)
+}
+
+// Checks the memory used against the board limits and the size budget of the target
+func checkSize(target types.Target, board *toolchain.Board, total size.Sections) []string {
+    var flash, ram int64
+    if board != nil {
+        flash, ram = board.Flash, board.Ram
+    }
+    violations := make([]string, 0, 2)
+    check := func(memory string, used int64, available int64, budget string) {
+        if available > 0 && used > available {
+            violations = append(violations, fmt.Sprintf("%s uses %d bytes of %s, the board only has %d",
+                target.GetName(), used, memory, available))
+            return
+        }
+        limit, err := size.ParseLimit(budget, available)
+        if err != nil {
+            violations = append(violations, fmt.Sprintf("%s: size_budget %s: %s", target.GetName(), memory, err))
+        } else if limit > 0 && used > limit {
+            violations = append(violations, fmt.Sprintf("%s uses %d bytes of %s, over its budget of %d",
+                target.GetName(), used, memory, limit))
+        }
+    }
+    check("flash", total.Flash(), flash, target.GetSizeBudget().GetFlash())
+    check("ram", total.Ram(), ram, target.GetSizeBudget().GetRam())
+    return violations
+}
+
+// Logs the memory used by the firmware and each of its libraries
+func logSize(target types.Target, board *toolchain.Board, total size.Sections, libraries []librarySize) {
+    var flash, ram int64
+    log.Infoln()
+    log.Info(log.Cyan, "Memory usage of ")
+    if board != nil {
+        flash, ram = board.Flash, board.Ram
+        log.Infoln(log.Magenta, "%s (%s, %s)", target.GetName(), board.Name, board.Mcu)
+    } else {
+        log.Infoln(log.Magenta, "%s", target.GetName())
+    }
+
+    buf := &bytes.Buffer{}
+    table := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+    fmt.Fprintf(table, "Flash:\t%s\t%s\n", formatUsage(total.Flash(), flash), percentOf(total.Flash(), flash))
+    fmt.Fprintf(table, "RAM:\t%s\t%s\n", formatUsage(total.Ram(), ram), percentOf(total.Ram(), ram))
+    if total.Eeprom > 0 {
+        fmt.Fprintf(table, "EEPROM:\t%d B\t\n", total.Eeprom)
+    }
+    table.Flush()
+    log.Info("%s", buf.String())
+
+    buf.Reset()
+    table = tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(table, "LIBRARY\tFLASH\tRAM")
+    for _, library := range libraries {
+        fmt.Fprintf(table, "%s\t%d\t%d\n", library.name, library.sections.Flash(), library.sections.Ram())
+    }
+    table.Flush()
+    lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+    log.Infoln(log.Cyan, "%s", lines[0])
+    for _, line := range lines[1:] {
+        log.Infoln("%s", line)
+    }
+    log.Verbln("library sizes include code the linker may remove as unused")
+}
+
+// Reports the memory used by every AVR target that was built and returns an
+// error if any of them does not fit on its board or exceeds its size budget
+func reportSizes(info *runInfo, targets []types.Target, results []targetResult) error {
+    violations := make([]string, 0, 4)
+    for i, target := range targets {
+        if target.GetPlatform() != constants.Avr || resultStatus(results[i]) != statusSuccess {
+            continue
+        }
+        total, libraries, err := readTargetSize(info, target)
+        if err != nil {
+            log.Warnln("could not read the size of %s: %s", target.GetName(), err.Error())
+            continue
+        }
+        board, err := avrBoard(target)
+        if err != nil {
+            board = nil
+        }
+        logSize(target, board, total, libraries)
+        violations = append(violations, checkSize(target, board, total)...)
+    }
+
+    for _, violation := range violations {
+        log.Errln("%s", violation)
+    }
+    if len(violations) > 0 {
+        return util.Error("%d size checks failed", len(violations))
+    }
+    return nil
+}
+
+// Owners of the objects a target is built from: its dependency packages, the target itself
+// and any other library built along with it, like the framework core
+func sizeOwners(info *runInfo, target types.Target) ([]size.Owner, error) {
+    objectDirs, err := filepath.Glob(sys.Path(binaryPath(info, target), "CMakeFiles", "*.dir"))
+    if err != nil {
+        return nil, err
+    }
+    objects := map[string][]string{}
+    names := make([]string, 0, len(objectDirs))
+    for _, dir := range objectDirs {
+        name := strings.TrimSuffix(filepath.Base(dir), ".dir")
+        err := filepath.Walk(dir, func(path string, fileInfo os.FileInfo, err error) error {
+            if err == nil && !fileInfo.IsDir() && (filepath.Ext(path) == ".o" || filepath.Ext(path) == ".obj") {
+                objects[name] = append(objects[name], path)
+            }
+            return err
+        })
+        if err != nil {
+            return nil, err
+        }
+        names = append(names, name)
+    }
+
+    buildTargets, err := dependencies.CreateBuildTargets(info.directory, target)
+    if err != nil {
+        return nil, err
+    }
+    owners := make([]size.Owner, 0, len(objects))
+    added := map[string]bool{}
+    add := func(name string) {
+        if files, exists := objects[name]; exists && !added[name] {
+            owners = append(owners, size.Owner{Name: name, Objects: files})
+            added[name] = true
+        }
+    }
+    for buildTarget := range buildTargets.TargetIterator() {
+        if buildTarget.Name != dependencies.MainTarget {
+            add(buildTarget.Name + "__" + buildTarget.Version)
+        }
+    }
+    add(target.GetName())
+    sort.Strings(names)
+    for _, name := range names {
+        add(name)
+    }
+    return owners, nil
+}
+
+func logPackageSize(report *size.Report) {
+    buf := &bytes.Buffer{}
+    table := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(table, "PACKAGE\tFLASH\tRAM\tFLASH %")
+    for _, usage := range report.Packages {
+        fmt.Fprintf(table, "%s\t%d\t%d\t%s\n", usage.Name, usage.Flash, usage.Ram,
+            percentOf(usage.Flash, report.Total.Flash))
+    }
+    fmt.Fprintf(table, "TOTAL\t%d\t%d\t\n", report.Total.Flash, report.Total.Ram)
+    table.Flush()
+
+    lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+    log.Infoln()
+    log.Info(log.Cyan, "Size by package of ")
+    log.Infoln(log.Magenta, report.Target)
+    log.Infoln(log.Cyan, "%s", lines[0])
+    for _, line := range lines[1 : len(lines)-1] {
+        log.Infoln("%s", line)
+    }
+    log.Infoln(log.Cyan, "%s", lines[len(lines)-1])
+}
+
+func formatDelta(delta int64) string {
+    if delta > 0 {
+        return fmt.Sprintf("+%d", delta)
+    }
+    return fmt.Sprintf("%d", delta)
+}
+
+// Logs how the size of each package changed since a previous report
+func logSizeDiff(old *size.Report, current *size.Report) {
+    buf := &bytes.Buffer{}
+    table := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(table, "PACKAGE\tFLASH\tDELTA\tRAM\tDELTA")
+    changes := size.Diff(old, current)
+    for _, change := range changes {
+        fmt.Fprintf(table, "%s\t%d -> %d\t%s\t%d -> %d\t%s\n", change.Name, change.OldFlash, change.NewFlash,
+            formatDelta(change.FlashDelta()), change.OldRam, change.NewRam, formatDelta(change.RamDelta()))
+    }
+    table.Flush()
+
+    lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+    log.Infoln()
+    log.Info(log.Cyan, "Size changes of ")
+    log.Infoln(log.Magenta, current.Target)
+    log.Infoln(log.Cyan, "%s", lines[0])
+    for i, line := range lines[1:] {
+        switch {
+        case changes[i].FlashDelta() > 0 || (changes[i].FlashDelta() == 0 && changes[i].RamDelta() > 0):
+            log.Infoln(log.Red, "%s", line)
+        case changes[i].FlashDelta() < 0 || changes[i].RamDelta() < 0:
+            log.Infoln(log.Green, "%s", line)
+        default:
+            log.Infoln("%s", line)
+        }
+    }
+    log.Info(log.Cyan, "Total flash %s, RAM %s", formatDelta(current.Total.Flash-old.Total.Flash),
+        formatDelta(current.Total.Ram-old.Total.Ram))
+    log.Infoln()
+}
+
+func readSizeReports(path string) ([]*size.Report, error) {
+    file, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+    return size.ReadReports(file)
+}
+
+// Shows the memory used by built targets, either per library with the board limits
+// or attributed to packages and compared against a previous report
+func (info *runInfo) size(targets []types.Target) error {
+    jsonPath := info.context.String("json")
+    diffPath := info.context.String("diff")
+    byPackage := info.context.Bool("by-package") || jsonPath != "" || diffPath != ""
+
+    var oldReports []*size.Report
+    if diffPath != "" {
+        var err error
+        if oldReports, err = readSizeReports(diffPath); err != nil {
+            return util.Error("could not read size report %s: %s", diffPath, err.Error())
+        }
+    }
+
+    reports := make([]*size.Report, 0, len(targets))
+    violations := make([]string, 0, 4)
+    for _, target := range targets {
+        file := sys.Path(binaryPath(info, target), target.GetName()+platformExtension(target.GetPlatform()))
+        if !sys.Exists(file) {
+            return util.Error("target %s has not been built, run wio build first", target.GetName())
+        }
+        var board *toolchain.Board
+        if target.GetPlatform() == constants.Avr {
+            board, _ = avrBoard(target)
+        }
+
+        if !byPackage {
+            total, libraries, err := readTargetSize(info, target)
+            if err != nil {
+                return err
+            }
+            logSize(target, board, total, libraries)
+            violations = append(violations, checkSize(target, board, total)...)
+            continue
+        }
+
+        owners, err := sizeOwners(info, target)
+        if err != nil {
+            return err
+        }
+        report, err := size.Attribute(file, owners)
+        if err != nil {
+            return err
+        }
+        report.Target = target.GetName()
+        report.Board = target.GetBoard()
+        reports = append(reports, report)
+        logPackageSize(report)
+
+        for _, old := range oldReports {
+            if old.Target == report.Target {
+                logSizeDiff(old, report)
+            }
+        }
+    }
+
+    if jsonPath != "" {
+        if err := writeReportFile(jsonPath, func(writer io.Writer) error {
+            return size.WriteReports(writer, reports)
+        }); err != nil {
+            return err
+        }
+        log.Info(log.Cyan, "Size report written to ")
+        log.Infoln(log.Green, jsonPath)
+    }
+
+    for _, violation := range violations {
+        log.Errln("%s", violation)
+    }
+    if len(violations) > 0 {
+        return util.Error("%d size checks failed", len(violations))
+    }
+    return nil
+}
@@ -0,0 +1,91 @@
+package size
+
+import (
+    "bytes"
+    "io/ioutil"
+    "strconv"
+    "strings"
+    "wio/pkg/util"
+)
+
+const (
+    archiveMagic      = "!<arch>\n"
+    archiveHeaderSize = 60
+)
+
+// File stored in a static library
+type member struct {
+    Name string
+    Data []byte
+}
+
+// Reads the members of a static library in the GNU or BSD ar format.
+// The symbol table and the long name table are not returned
+func readArchive(path string) ([]member, error) {
+    data, err := ioutil.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    return parseArchive(data)
+}
+
+func parseArchive(data []byte) ([]member, error) {
+    if !bytes.HasPrefix(data, []byte(archiveMagic)) {
+        return nil, util.Error("not an ar archive")
+    }
+    members := make([]member, 0, 16)
+    longNames := []byte{}
+    offset := len(archiveMagic)
+
+    for offset+archiveHeaderSize <= len(data) {
+        header := data[offset : offset+archiveHeaderSize]
+        if string(header[58:60]) != "`\n" {
+            return nil, util.Error("corrupt ar header at offset %d", offset)
+        }
+        name := strings.TrimRight(string(header[0:16]), " ")
+        size, err := strconv.Atoi(strings.TrimSpace(string(header[48:58])))
+        if err != nil || size < 0 {
+            return nil, util.Error("corrupt ar member size at offset %d", offset)
+        }
+        offset += archiveHeaderSize
+        if offset+size > len(data) {
+            return nil, util.Error("truncated ar member %s", name)
+        }
+        content := data[offset : offset+size]
+        // members are aligned to two bytes
+        offset += size + size%2
+
+        switch {
+        case name == "/" || name == "/SYM64/" || strings.HasPrefix(name, "__.SYMDEF"):
+            continue
+        case name == "//":
+            longNames = content
+            continue
+        case strings.HasPrefix(name, "#1/"):
+            // BSD: the name is stored in front of the content
+            length, err := strconv.Atoi(name[3:])
+            if err != nil || length > len(content) {
+                return nil, util.Error("corrupt ar member name %s", name)
+            }
+            name = strings.TrimRight(string(content[:length]), "\x00")
+            content = content[length:]
+            if strings.HasPrefix(name, "__.SYMDEF") {
+                continue
+            }
+        case strings.HasPrefix(name, "/"):
+            // GNU: offset into the long name table
+            start, err := strconv.Atoi(name[1:])
+            if err != nil || start > len(longNames) {
+                return nil, util.Error("corrupt ar member name %s", name)
+            }
+            name = string(longNames[start:])
+            if end := strings.Index(name, "/\n"); end >= 0 {
+                name = name[:end]
+            }
+        default:
+            name = strings.TrimSuffix(name, "/")
+        }
+        members = append(members, member{Name: name, Data: content})
+    }
+    return members, nil
+}
@@ -0,0 +1,238 @@
+package size
+
+import (
+    "debug/elf"
+    "encoding/json"
+    "io"
+    "sort"
+)
+
+// Name of the row with the memory that could not be attributed to any owner,
+// such as the C library, startup code and padding between symbols
+const Other = "(other)"
+
+// Package or target that the objects it was compiled into belong to
+type Owner struct {
+    Name    string
+    Objects []string
+}
+
+// Memory used by one owner
+type Usage struct {
+    Name  string `json:"name"`
+    Flash int64  `json:"flash"`
+    Ram   int64  `json:"ram"`
+}
+
+// Memory of a firmware attributed to the packages it is built from
+type Report struct {
+    Target   string  `json:"target"`
+    Board    string  `json:"board,omitempty"`
+    Total    Usage   `json:"total"`
+    Packages []Usage `json:"packages"`
+}
+
+// Symbols that take up memory on the device
+func isSizedSymbol(symbol elf.Symbol) bool {
+    symbolType := elf.ST_TYPE(symbol.Info)
+    return symbol.Section != elf.SHN_UNDEF && symbol.Section < elf.SHN_LORESERVE &&
+        (symbolType == elf.STT_FUNC || symbolType == elf.STT_OBJECT)
+}
+
+// Symbols defined in an object file that take up memory
+func definedSymbols(path string) ([]elf.Symbol, error) {
+    file, err := elf.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+    symbols, err := file.Symbols()
+    if err != nil && err != elf.ErrNoSymbols {
+        return nil, err
+    }
+    defined := make([]elf.Symbol, 0, len(symbols))
+    for _, symbol := range symbols {
+        if isSizedSymbol(symbol) {
+            defined = append(defined, symbol)
+        }
+    }
+    return defined, nil
+}
+
+// Owner given to local symbols that several owners define
+const ambiguousOwner = -1
+
+// Index of the owner defining each symbol. Global symbols defined by several owners, like
+// inline functions, go to the first of them. Local symbols, like static helpers and
+// constructors of globals, often have the same name in several owners and cannot be told
+// apart in the executable, so those are not attributed to any of them
+type symbolOwners struct {
+    global map[string]int
+    local  map[string]int
+}
+
+func newSymbolOwners() *symbolOwners {
+    return &symbolOwners{global: map[string]int{}, local: map[string]int{}}
+}
+
+func (o *symbolOwners) add(owner int, symbol elf.Symbol) {
+    if elf.ST_BIND(symbol.Info) != elf.STB_LOCAL {
+        if _, exists := o.global[symbol.Name]; !exists {
+            o.global[symbol.Name] = owner
+        }
+        return
+    }
+    if previous, exists := o.local[symbol.Name]; !exists {
+        o.local[symbol.Name] = owner
+    } else if previous != owner {
+        o.local[symbol.Name] = ambiguousOwner
+    }
+}
+
+// Owner of a symbol of the executable, false if it has none or could be one of several
+func (o *symbolOwners) find(symbol elf.Symbol) (int, bool) {
+    owners := o.global
+    if elf.ST_BIND(symbol.Info) == elf.STB_LOCAL {
+        owners = o.local
+    }
+    owner, exists := owners[symbol.Name]
+    return owner, exists && owner != ambiguousOwner
+}
+
+// Attributes every symbol of the linked executable to the owner whose objects define it.
+// What cannot be attributed is reported as Other
+func Attribute(elfPath string, owners []Owner) (*Report, error) {
+    symbolOwners := newSymbolOwners()
+    for i, owner := range owners {
+        for _, object := range owner.Objects {
+            symbols, err := definedSymbols(object)
+            if err != nil {
+                return nil, err
+            }
+            for _, symbol := range symbols {
+                symbolOwners.add(i, symbol)
+            }
+        }
+    }
+
+    file, err := elf.Open(elfPath)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+    symbols, err := file.Symbols()
+    if err != nil && err != elf.ErrNoSymbols {
+        return nil, err
+    }
+
+    total := readSections(file)
+    usage := make([]Sections, len(owners))
+    for _, symbol := range symbols {
+        index, exists := symbolOwners.find(symbol)
+        if !exists || !isSizedSymbol(symbol) || int(symbol.Section) >= len(file.Sections) {
+            continue
+        }
+        usage[index].addKind(sectionKind(file.Sections[symbol.Section]), int64(symbol.Size))
+    }
+
+    report := &Report{
+        Total: Usage{Name: "total", Flash: total.Flash(), Ram: total.Ram()},
+    }
+    attributed := Usage{}
+    for i, owner := range owners {
+        report.Packages = append(report.Packages, Usage{
+            Name:  owner.Name,
+            Flash: usage[i].Flash(),
+            Ram:   usage[i].Ram(),
+        })
+        attributed.Flash += usage[i].Flash()
+        attributed.Ram += usage[i].Ram()
+    }
+    report.Packages = append(report.Packages, Usage{
+        Name:  Other,
+        Flash: total.Flash() - attributed.Flash,
+        Ram:   total.Ram() - attributed.Ram,
+    })
+    sortUsage(report.Packages)
+    return report, nil
+}
+
+// Sorts by flash and then by RAM, largest first
+func sortUsage(list []Usage) {
+    sort.SliceStable(list, func(i, j int) bool {
+        if list[i].Flash != list[j].Flash {
+            return list[i].Flash > list[j].Flash
+        }
+        return list[i].Ram > list[j].Ram
+    })
+}
+
+// Change in the memory used by a package between two builds
+type Change struct {
+    Name     string
+    OldFlash int64
+    NewFlash int64
+    OldRam   int64
+    NewRam   int64
+}
+
+func (c Change) FlashDelta() int64 {
+    return c.NewFlash - c.OldFlash
+}
+
+func (c Change) RamDelta() int64 {
+    return c.NewRam - c.OldRam
+}
+
+func abs(value int64) int64 {
+    if value < 0 {
+        return -value
+    }
+    return value
+}
+
+// Compares the packages of two reports, including the ones that were added or removed.
+// Changes are sorted by how much the flash used changed, biggest first
+func Diff(old *Report, current *Report) []Change {
+    changes := make([]Change, 0, len(current.Packages))
+    indexes := map[string]int{}
+    for _, usage := range old.Packages {
+        indexes[usage.Name] = len(changes)
+        changes = append(changes, Change{Name: usage.Name, OldFlash: usage.Flash, OldRam: usage.Ram})
+    }
+    for _, usage := range current.Packages {
+        index, exists := indexes[usage.Name]
+        if !exists {
+            index = len(changes)
+            changes = append(changes, Change{Name: usage.Name})
+        }
+        changes[index].NewFlash = usage.Flash
+        changes[index].NewRam = usage.Ram
+    }
+    sort.SliceStable(changes, func(i, j int) bool {
+        if abs(changes[i].FlashDelta()) != abs(changes[j].FlashDelta()) {
+            return abs(changes[i].FlashDelta()) > abs(changes[j].FlashDelta())
+        }
+        return abs(changes[i].RamDelta()) > abs(changes[j].RamDelta())
+    })
+    return changes
+}
+
+// Writes reports of one or more targets as a JSON array
+func WriteReports(writer io.Writer, reports []*Report) error {
+    data, err := json.MarshalIndent(reports, "", "  ")
+    if err != nil {
+        return err
+    }
+    _, err = writer.Write(append(data, '\n'))
+    return err
+}
+
+// Reads reports written by WriteReports
+func ReadReports(reader io.Reader) ([]*Report, error) {
+    var reports []*Report
+    if err := json.NewDecoder(reader).Decode(&reports); err != nil {
+        return nil, err
+    }
+    return reports, nil
+}
@@ -0,0 +1,44 @@
+package size
+
+import (
+    "strconv"
+    "strings"
+    "wio/pkg/util"
+)
+
+var limitUnits = map[string]int64{
+    "":   1,
+    "B":  1,
+    "K":  1024,
+    "KB": 1024,
+    "M":  1024 * 1024,
+    "MB": 1024 * 1024,
+}
+
+// Parses a memory limit given in bytes, with a K or M suffix, or as a percentage
+// of the available memory, e.g. "30000", "28K" or "90%". An empty limit is 0
+func ParseLimit(value string, available int64) (int64, error) {
+    value = strings.ToUpper(strings.Replace(value, " ", "", -1))
+    if value == "" {
+        return 0, nil
+    }
+
+    if strings.HasSuffix(value, "%") {
+        percent, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+        if err != nil || percent < 0 {
+            return 0, util.Error("invalid memory limit %s", value)
+        }
+        if available <= 0 {
+            return 0, util.Error("memory limit %s is relative but the available memory is not known", value)
+        }
+        return int64(percent / 100 * float64(available)), nil
+    }
+
+    number := strings.TrimRight(value, "BKM")
+    unit, exists := limitUnits[value[len(number):]]
+    amount, err := strconv.ParseFloat(number, 64)
+    if !exists || err != nil || amount < 0 {
+        return 0, util.Error("invalid memory limit %s", value)
+    }
+    return int64(amount * float64(unit)), nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2018 Waterloop. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package size reads the memory used by AVR firmware and the libraries it is linked from
+// and checks it against the limits of the board and the budget of the target
+package size
+
+import (
+    "bytes"
+    "debug/elf"
+    "strings"
+)
+
+// Sizes of the sections of an ELF file grouped the same way avr-size does
+type Sections struct {
+    // code and constants in program memory
+    Text int64
+    // initialized variables, which are stored in flash and copied to RAM
+    Data int64
+    // zeroed and uninitialized variables
+    Bss    int64
+    Eeprom int64
+}
+
+// Bytes of program memory used
+func (s Sections) Flash() int64 {
+    return s.Text + s.Data
+}
+
+// Bytes of RAM used by static data, the stack and heap come on top of this
+func (s Sections) Ram() int64 {
+    return s.Data + s.Bss
+}
+
+func (s Sections) Add(other Sections) Sections {
+    return Sections{
+        Text:   s.Text + other.Text,
+        Data:   s.Data + other.Data,
+        Bss:    s.Bss + other.Bss,
+        Eeprom: s.Eeprom + other.Eeprom,
+    }
+}
+
+// Object files put every function and variable in its own section, e.g. ".text._Z4loopv"
+func hasSectionPrefix(name string, prefix string) bool {
+    return name == prefix || strings.HasPrefix(name, prefix+".")
+}
+
+const (
+    kindText   = "text"
+    kindData   = "data"
+    kindBss    = "bss"
+    kindEeprom = "eeprom"
+)
+
+// Group a section belongs to by its name or "" if it does not take up memory on the device
+func sectionKind(section *elf.Section) string {
+    if section.Flags&elf.SHF_ALLOC == 0 {
+        return ""
+    }
+    name := section.Name
+    switch {
+    case hasSectionPrefix(name, ".text"), hasSectionPrefix(name, ".progmem"),
+        hasSectionPrefix(name, ".bootloader"):
+        return kindText
+    case hasSectionPrefix(name, ".data"), hasSectionPrefix(name, ".rodata"):
+        return kindData
+    case hasSectionPrefix(name, ".bss"), hasSectionPrefix(name, ".noinit"):
+        return kindBss
+    case hasSectionPrefix(name, ".eeprom"):
+        return kindEeprom
+    }
+    return ""
+}
+
+// Adds a number of bytes to the given group
+func (s *Sections) addKind(kind string, size int64) {
+    switch kind {
+    case kindText:
+        s.Text += size
+    case kindData:
+        s.Data += size
+    case kindBss:
+        s.Bss += size
+    case kindEeprom:
+        s.Eeprom += size
+    }
+}
+
+func readSections(file *elf.File) Sections {
+    sections := Sections{}
+    for _, section := range file.Sections {
+        sections.addKind(sectionKind(section), int64(section.Size))
+    }
+    return sections
+}
+
+// Reads the section sizes of a linked ELF executable or an object file
+func ReadElf(path string) (Sections, error) {
+    file, err := elf.Open(path)
+    if err != nil {
+        return Sections{}, err
+    }
+    defer file.Close()
+    return readSections(file), nil
+}
+
+// Adds up the section sizes of all the objects in a static library. Sections the
+// linker drops because nothing uses them are included, so this is an upper bound
+func ReadArchive(path string) (Sections, error) {
+    members, err := readArchive(path)
+    if err != nil {
+        return Sections{}, err
+    }
+    total := Sections{}
+    for _, member := range members {
+        file, err := elf.NewFile(bytes.NewReader(member.Data))
+        if err != nil {
+            // libraries may contain files that are not objects
+            continue
+        }
+        total = total.Add(readSections(file))
+    }
+    return total, nil
+}
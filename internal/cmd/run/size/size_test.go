@@ -0,0 +1,146 @@
+package size
+
+import (
+    "bytes"
+    "debug/elf"
+    "fmt"
+    "strings"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func arHeader(name string, size int) string {
+    return fmt.Sprintf("%-16s%-12s%-6s%-6s%-8s%-10d`\n", name, "0", "0", "0", "644", size)
+}
+
+func TestParseArchive(t *testing.T) {
+    longNames := "a_very_long_object_name.cpp.o/\n"
+    archive := archiveMagic +
+        arHeader("/", 4) + "\x00\x00\x00\x00" +
+        arHeader("//", len(longNames)) + longNames + "\n" +
+        arHeader("short.o/", 3) + "abc" + "\n" +
+        arHeader("/0", 2) + "de" +
+        arHeader("#1/8", 10) + "bsd.o\x00\x00\x00" + "fg"
+
+    members, err := parseArchive([]byte(archive))
+    assert.Nil(t, err)
+    assert.Equal(t, 3, len(members))
+    assert.Equal(t, "short.o", members[0].Name)
+    assert.Equal(t, "abc", string(members[0].Data))
+    assert.Equal(t, "a_very_long_object_name.cpp.o", members[1].Name)
+    assert.Equal(t, "de", string(members[1].Data))
+    assert.Equal(t, "bsd.o", members[2].Name)
+    assert.Equal(t, "fg", string(members[2].Data))
+
+    _, err = parseArchive([]byte("not an archive"))
+    assert.NotNil(t, err)
+
+    _, err = parseArchive([]byte(archiveMagic + strings.Replace(arHeader("x.o/", 100), "`\n", "xx", 1)))
+    assert.NotNil(t, err)
+}
+
+func TestParseLimit(t *testing.T) {
+    cases := map[string]int64{
+        "":       0,
+        "30000":  30000,
+        "28K":    28672,
+        "1.5 KB": 1536,
+        "50%":    16128,
+        "100%":   32256,
+    }
+    for value, expected := range cases {
+        limit, err := ParseLimit(value, 32256)
+        assert.Nil(t, err, value)
+        assert.Equal(t, expected, limit, value)
+    }
+
+    for _, value := range []string{"abc", "12Q", "-5", "x%"} {
+        _, err := ParseLimit(value, 32256)
+        assert.NotNil(t, err, value)
+    }
+    _, err := ParseLimit("50%", 0)
+    assert.NotNil(t, err)
+}
+
+func TestSections(t *testing.T) {
+    sections := Sections{Text: 1000, Data: 20, Bss: 100}.Add(Sections{Text: 10, Bss: 5, Eeprom: 4})
+    assert.Equal(t, int64(1030), sections.Flash())
+    assert.Equal(t, int64(125), sections.Ram())
+    assert.Equal(t, int64(4), sections.Eeprom)
+}
+
+func TestDiff(t *testing.T) {
+    old := &Report{Target: "main", Packages: []Usage{
+        {Name: "main", Flash: 1000, Ram: 100},
+        {Name: "pkg-list__0.0.1", Flash: 400, Ram: 20},
+        {Name: "pkg-old__1.0.0", Flash: 50, Ram: 0},
+    }}
+    current := &Report{Target: "main", Packages: []Usage{
+        {Name: "main", Flash: 1010, Ram: 100},
+        {Name: "pkg-list__0.0.1", Flash: 900, Ram: 20},
+        {Name: "pkg-new__2.0.0", Flash: 0, Ram: 64},
+    }}
+
+    changes := Diff(old, current)
+    assert.Equal(t, 4, len(changes))
+    assert.Equal(t, "pkg-list__0.0.1", changes[0].Name)
+    assert.Equal(t, int64(500), changes[0].FlashDelta())
+    assert.Equal(t, "pkg-old__1.0.0", changes[1].Name)
+    assert.Equal(t, int64(-50), changes[1].FlashDelta())
+    assert.Equal(t, "main", changes[2].Name)
+    assert.Equal(t, "pkg-new__2.0.0", changes[3].Name)
+    assert.Equal(t, int64(64), changes[3].RamDelta())
+}
+
+func TestReports(t *testing.T) {
+    reports := []*Report{{
+        Target:   "main",
+        Board:    "uno",
+        Total:    Usage{Name: "total", Flash: 1400, Ram: 120},
+        Packages: []Usage{{Name: "main", Flash: 1000, Ram: 100}, {Name: Other, Flash: 400, Ram: 20}},
+    }}
+    buf := &bytes.Buffer{}
+    assert.Nil(t, WriteReports(buf, reports))
+
+    decoded, err := ReadReports(buf)
+    assert.Nil(t, err)
+    assert.Equal(t, reports, decoded)
+}
+
+func TestSymbolOwners(t *testing.T) {
+    symbol := func(name string, bind elf.SymBind) elf.Symbol {
+        return elf.Symbol{Name: name, Info: elf.ST_INFO(bind, elf.STT_FUNC)}
+    }
+    owners := newSymbolOwners()
+    owners.add(0, symbol("setup", elf.STB_GLOBAL))
+    owners.add(0, symbol("helper", elf.STB_LOCAL))
+    owners.add(0, symbol("_GLOBAL__sub_I_main", elf.STB_LOCAL))
+    owners.add(1, symbol("setup", elf.STB_WEAK))
+    owners.add(1, symbol("_GLOBAL__sub_I_main", elf.STB_LOCAL))
+    owners.add(1, symbol("format", elf.STB_LOCAL))
+    owners.add(1, symbol("format", elf.STB_LOCAL))
+
+    tests := []struct {
+        symbol elf.Symbol
+        owner  int
+        found  bool
+    }{
+        {symbol("setup", elf.STB_GLOBAL), 0, true},
+        {symbol("helper", elf.STB_LOCAL), 0, true},
+        // defined by one owner in several of its objects
+        {symbol("format", elf.STB_LOCAL), 1, true},
+        // static in two owners, cannot tell which one it came from
+        {symbol("_GLOBAL__sub_I_main", elf.STB_LOCAL), 0, false},
+        // a local symbol is not matched with a global one of the same name
+        {symbol("setup", elf.STB_LOCAL), 0, false},
+        {symbol("missing", elf.STB_GLOBAL), 0, false},
+    }
+    for _, test := range tests {
+        owner, found := owners.find(test.symbol)
+        assert.Equal(t, test.found, found, test.symbol.Name)
+        if test.found {
+            assert.Equal(t, test.owner, owner, test.symbol.Name)
+        }
+    }
+}
@@ -0,0 +1,141 @@
+package run
+
+import (
+    "bytes"
+    "fmt"
+    "os"
+    "strings"
+    "text/tabwriter"
+    "time"
+    "wio/internal/cmd/run/diagnostics"
+    "wio/internal/types"
+    "wio/pkg/log"
+    "wio/pkg/util"
+    "wio/pkg/util/sys"
+
+    "github.com/fatih/color"
+)
+
+const (
+    statusSuccess = "success"
+    statusFailure = "failure"
+    statusSkipped = "skipped"
+)
+
+var statusColors = map[string]*color.Color{
+    statusSuccess: log.Green,
+    statusFailure: log.Red,
+    statusSkipped: log.Yellow,
+}
+
+func resultStatus(result targetResult) string {
+    if result.skipped {
+        return statusSkipped
+    } else if result.err != nil {
+        return statusFailure
+    }
+    return statusSuccess
+}
+
+// Size of the binary produced by the target, or -1 if there is none
+func artifactSize(info *runInfo, target types.Target) int64 {
+    file := sys.Path(binaryPath(info, target), target.GetName()+platformExtension(target.GetPlatform()))
+    stat, err := os.Stat(file)
+    if err != nil {
+        return -1
+    }
+    return stat.Size()
+}
+
+func formatSize(size int64) string {
+    switch {
+    case size < 0:
+        return "-"
+    case size < 1024:
+        return fmt.Sprintf("%d B", size)
+    case size < 1024*1024:
+        return fmt.Sprintf("%.1f KiB", float64(size)/1024)
+    default:
+        return fmt.Sprintf("%.1f MiB", float64(size)/(1024*1024))
+    }
+}
+
+func formatDuration(result targetResult) string {
+    if result.skipped {
+        return "-"
+    }
+    return result.duration.Round(100 * time.Millisecond).String()
+}
+
+// Prints a table with the outcome of every target followed by the errors of the failed ones
+func printSummary(info *runInfo, targets []types.Target, results []targetResult) {
+    buf := &bytes.Buffer{}
+    table := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(table, "TARGET\tPLATFORM\tBOARD\tSTATUS\tDURATION\tSIZE")
+    for i, target := range targets {
+        result := results[i]
+        size := int64(-1)
+        if resultStatus(result) == statusSuccess {
+            size = artifactSize(info, target)
+        }
+        board := target.GetBoard()
+        if board == "" {
+            board = "-"
+        }
+        fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n", target.GetName(), target.GetPlatform(), board,
+            resultStatus(result), formatDuration(result), formatSize(size))
+    }
+    table.Flush()
+
+    lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+    log.Infoln()
+    log.Infoln(log.Cyan, "%s", lines[0])
+    for i, line := range lines[1:] {
+        log.Infoln(statusColors[resultStatus(results[i])], "%s", line)
+    }
+
+    for i, result := range results {
+        if result.err != nil {
+            log.Errln("%s: %s", targets[i].GetName(), result.err)
+            if info.context.Bool("build-log") {
+                log.Errln("%s: full output in %s", targets[i].GetName(), buildLogPath(result.dir))
+            }
+        }
+    }
+}
+
+// Checks the diagnostics flags before anything is built. JSON goes to a file since standard
+// output also carries the logs and the output of the compilers
+func checkDiagnosticsFlags(info *runInfo) error {
+    switch format := info.context.String("diagnostics-format"); format {
+    case "", "text", "none":
+        return nil
+    case "json":
+        if info.context.String("diagnostics-output") == "" {
+            return util.Error("--diagnostics-format json needs a file to write to, set --diagnostics-output")
+        }
+        return nil
+    default:
+        return util.Error("invalid diagnostics format %s. Try one of text, json or none", format)
+    }
+}
+
+// Parses compiler diagnostics from the output of the targets and reports them in the requested format
+func reportDiagnostics(info *runInfo, targets []types.Target, results []targetResult) error {
+    set := diagnostics.NewSet(info.directory)
+    for i, result := range results {
+        set.Parse(targets[i].GetName(), result.output)
+    }
+
+    switch format := info.context.String("diagnostics-format"); format {
+    case "", "text":
+        set.Log()
+        return nil
+    case "json":
+        return writeReportFile(info.context.String("diagnostics-output"), set.WriteJson)
+    case "none":
+        return nil
+    default:
+        return util.Error("invalid diagnostics format %s. Try one of text, json or none", format)
+    }
+}
@@ -0,0 +1,75 @@
+package run
+
+import (
+    "bytes"
+    "errors"
+    "flag"
+    "io/ioutil"
+    "os"
+    "testing"
+    "time"
+    "wio/internal/constants"
+    "wio/internal/types"
+    "wio/pkg/log"
+    "wio/pkg/util/sys"
+
+    "github.com/fatih/color"
+    "github.com/stretchr/testify/assert"
+    "github.com/urfave/cli"
+)
+
+// Captures everything logged while the function runs, without colors
+func captureLogs(function func()) (string, string) {
+    noColor := color.NoColor
+    color.NoColor = true
+    out, err := &bytes.Buffer{}, &bytes.Buffer{}
+    prevOut, prevErr := log.SetOutput(out, err)
+    defer func() {
+        log.SetOutput(prevOut, prevErr)
+        color.NoColor = noColor
+    }()
+    function()
+    return out.String(), err.String()
+}
+
+func newTarget(name string, platform string, board string) types.Target {
+    target := &types.TargetImpl{Platform: platform, Board: board}
+    target.SetName(name)
+    return target
+}
+
+func TestPrintSummary(t *testing.T) {
+    dir, err := ioutil.TempDir("", "wio-summary")
+    assert.Nil(t, err)
+    defer os.RemoveAll(dir)
+
+    set := flag.NewFlagSet("build", flag.ContinueOnError)
+    set.Bool("build-log", true, "")
+    info := &runInfo{context: cli.NewContext(nil, set, nil), directory: dir}
+
+    targets := []types.Target{
+        newTarget("blink", constants.Avr, "uno"),
+        newTarget("tests", constants.Native, ""),
+        newTarget("later", constants.Native, ""),
+    }
+    binary := binaryPath(info, targets[0])
+    assert.Nil(t, os.MkdirAll(binary, os.ModePerm))
+    assert.Nil(t, ioutil.WriteFile(sys.Path(binary, "blink.elf"), make([]byte, 2048), os.ModePerm))
+
+    results := []targetResult{
+        {dir: "targets/blink", duration: 1240 * time.Millisecond},
+        {dir: "targets/tests", duration: 300 * time.Millisecond, err: errors.New("make failed")},
+        {dir: "targets/later", skipped: true},
+    }
+    out, errOut := captureLogs(func() {
+        printSummary(info, targets, results)
+    })
+
+    expected := "\n" +
+        "TARGET  PLATFORM  BOARD  STATUS   DURATION  SIZE\n" +
+        "blink   avr       uno    success  1.2s      2.0 KiB\n" +
+        "tests   native    -      failure  300ms     -\n" +
+        "later   native    -      skipped  -         -\n"
+    assert.Equal(t, expected, out)
+    assert.Equal(t, "ERR tests: make failed\nERR tests: full output in "+buildLogPath("targets/tests")+"\n", errOut)
+}
@@ -0,0 +1,176 @@
+package run
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "strings"
+    "text/tabwriter"
+    "time"
+    "wio/internal/cmd/run/coverage"
+    "wio/internal/cmd/run/tests"
+    "wio/internal/types"
+    "wio/pkg/log"
+    "wio/pkg/util"
+    "wio/pkg/util/sys"
+)
+
+var testStatuses = map[string]string{
+    tests.Pass: statusSuccess,
+    tests.Fail: statusFailure,
+    tests.Skip: statusSkipped,
+}
+
+// Builds and runs the test targets and reports their results
+func (info *runInfo) test(targets []types.Target) error {
+    log.Infoln(log.Cyan, "Generating files ... ")
+    targetDirs, err := configureTargets(info, targets)
+    if err != nil {
+        return err
+    }
+
+    log.Infoln(log.Cyan, "Building test targets")
+    log.Infoln(log.Magenta, "Running with JOBS=%d", info.jobs)
+    output := outputOptions{
+        group:   info.context.Bool("group-output"),
+        logFile: info.context.Bool("build-log"),
+    }
+    results := asyncBuildTargets(targetDirs, info.jobs, false, output)
+
+    log.Infoln(log.Cyan, "Running test targets")
+    var report *coverage.Report
+    if info.variant != nil && info.variant.coverage {
+        report = coverage.NewReport(info.directory, info.context.Bool("coverage-deps"))
+    }
+    suites := make([]*tests.Suite, 0, len(targets))
+    for i, target := range targets {
+        suite := &tests.Suite{Name: target.GetName()}
+        if results[i].err != nil {
+            suite.Error = fmt.Sprintf("build failed: %s", results[i].err)
+        } else if err := runTest(info, target, i, suite, report); err != nil {
+            suite.Error = err.Error()
+        }
+        suites = append(suites, suite)
+    }
+
+    printTestSummary(suites)
+    if report != nil {
+        if err := writeCoverage(info, report); err != nil {
+            return err
+        }
+    }
+    if junitPath := info.context.String("junit"); junitPath != "" {
+        if err := writeJunit(junitPath, suites); err != nil {
+            return err
+        }
+        log.Info(log.Cyan, "JUnit report written to ")
+        log.Infoln(log.Green, junitPath)
+    }
+    return testsError(suites)
+}
+
+// Runs the test target and, when measuring coverage, adds the lines it executed to the report
+func runTest(info *runInfo, target types.Target, index int, suite *tests.Suite, report *coverage.Report) error {
+    if report == nil || !isCoverageTarget(info, target) {
+        return dispatchTestTarget(info, target, index, suite)
+    }
+    if err := resetCoverage(info, target); err != nil {
+        return err
+    }
+    if err := dispatchTestTarget(info, target, index, suite); err != nil {
+        return err
+    }
+    return collectCoverage(info, target, report)
+}
+
+// Runs a native test executable and records its output, exit code and parsed cases
+func runNativeTest(info *runInfo, target types.Target, index int, suite *tests.Suite) error {
+    output, err := outputOptions{group: info.context.Bool("group-output")}.open(index, targetPath(info, target))
+    if err != nil {
+        return err
+    }
+    file := sys.Path(binaryPath(info, target), target.GetName()+platformExtension(target.GetPlatform()))
+    suite.ExitCode, suite.Duration, err = testTarget(info.directory, file, info.context.String("args"),
+        info.context.Duration("timeout"), output)
+    if err != nil {
+        suite.Error = err.Error()
+    }
+    suite.Output = output.String()
+    suite.Cases = tests.Parse(suite.Output)
+    return output.Close(testStatuses[suite.Status()])
+}
+
+// Runs AVR test firmware in the simulator and records its output and exit code
+func runSimulatedTest(info *runInfo, target types.Target, index int, suite *tests.Suite) error {
+    output, err := outputOptions{group: info.context.Bool("group-output")}.open(index, targetPath(info, target))
+    if err != nil {
+        return err
+    }
+    start := time.Now()
+    suite.ExitCode, err = simulateTarget(info, target, output)
+    suite.Duration = time.Since(start)
+    if err != nil {
+        suite.Error = err.Error()
+    }
+    suite.Output = output.String()
+    suite.Cases = tests.Parse(suite.Output)
+    return output.Close(testStatuses[suite.Status()])
+}
+
+func printTestSummary(suites []*tests.Suite) {
+    buf := &bytes.Buffer{}
+    table := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(table, "TARGET\tSTATUS\tPASSED\tFAILED\tSKIPPED\tEXIT\tDURATION")
+    for _, suite := range suites {
+        exitCode := "-"
+        duration := "-"
+        if suite.Duration > 0 {
+            exitCode = fmt.Sprintf("%d", suite.ExitCode)
+            duration = suite.Duration.String()
+        }
+        fmt.Fprintf(table, "%s\t%s\t%d\t%d\t%d\t%s\t%s\n", suite.Name, suite.Status(), suite.Count(tests.Pass),
+            suite.Count(tests.Fail), suite.Count(tests.Skip), exitCode, duration)
+    }
+    table.Flush()
+
+    lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+    log.Infoln()
+    log.Infoln(log.Cyan, "%s", lines[0])
+    for i, line := range lines[1:] {
+        log.Infoln(statusColors[testStatuses[suites[i].Status()]], "%s", line)
+    }
+
+    for _, suite := range suites {
+        if suite.Skipped() {
+            log.Warnln("%s: skipped, %s", suite.Name, suite.SkipReason)
+            continue
+        }
+        if suite.Error != "" {
+            log.Errln("%s: %s", suite.Name, suite.Error)
+        }
+        for _, c := range suite.Cases {
+            if c.Status == tests.Fail {
+                log.Errln("%s: %s failed %s", suite.Name, c.Name, c.Message)
+            }
+        }
+    }
+}
+
+func writeJunit(path string, suites []*tests.Suite) error {
+    return writeReportFile(path, func(file io.Writer) error {
+        return tests.WriteJunit(file, suites)
+    })
+}
+
+func testsError(suites []*tests.Suite) error {
+    failed := make([]string, 0, len(suites))
+    for _, suite := range suites {
+        if suite.Failed() {
+            failed = append(failed, suite.Name)
+        }
+    }
+    if len(failed) > 0 {
+        return util.Error("%d of %d test targets failed: %s", len(failed), len(suites), strings.Join(failed, ", "))
+    }
+    return nil
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+    "encoding/xml"
+    "fmt"
+    "io"
+    "time"
+)
+
+type junitMessage struct {
+    Message string `xml:"message,attr,omitempty"`
+    Text    string `xml:",chardata"`
+}
+
+type junitCase struct {
+    Name      string        `xml:"name,attr"`
+    Classname string        `xml:"classname,attr"`
+    Time      string        `xml:"time,attr"`
+    Failure   *junitMessage `xml:"failure,omitempty"`
+    Error     *junitMessage `xml:"error,omitempty"`
+    Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitSuite struct {
+    Name      string      `xml:"name,attr"`
+    Tests     int         `xml:"tests,attr"`
+    Failures  int         `xml:"failures,attr"`
+    Errors    int         `xml:"errors,attr"`
+    Skipped   int         `xml:"skipped,attr"`
+    Time      string      `xml:"time,attr"`
+    Cases     []junitCase `xml:"testcase"`
+    SystemOut string      `xml:"system-out,omitempty"`
+}
+
+type junitSuites struct {
+    XMLName  xml.Name     `xml:"testsuites"`
+    Tests    int          `xml:"tests,attr"`
+    Failures int          `xml:"failures,attr"`
+    Errors   int          `xml:"errors,attr"`
+    Skipped  int          `xml:"skipped,attr"`
+    Time     string       `xml:"time,attr"`
+    Suites   []junitSuite `xml:"testsuite"`
+}
+
+func junitTime(duration time.Duration) string {
+    return fmt.Sprintf("%.3f", duration.Seconds())
+}
+
+// Converts a suite to JUnit. A suite without parsed cases becomes a single case
+// named after the target that passes or fails based on the exit code
+func toJunitSuite(suite *Suite) junitSuite {
+    result := junitSuite{
+        Name:      suite.Name,
+        Time:      junitTime(suite.Duration),
+        SystemOut: suite.Output,
+    }
+
+    switch {
+    case suite.Skipped():
+        result.Cases = append(result.Cases, junitCase{
+            Skipped: &junitMessage{Message: suite.SkipReason},
+        })
+        result.Skipped++
+    case suite.Error != "":
+        result.Cases = append(result.Cases, junitCase{
+            Error: &junitMessage{Message: suite.Error},
+        })
+        result.Errors++
+    case len(suite.Cases) <= 0:
+        testCase := junitCase{Time: result.Time}
+        if suite.ExitCode != 0 {
+            testCase.Failure = &junitMessage{Message: fmt.Sprintf("exit code %d", suite.ExitCode)}
+            result.Failures++
+        }
+        result.Cases = append(result.Cases, testCase)
+    default:
+        for _, c := range suite.Cases {
+            testCase := junitCase{Name: c.Name}
+            switch c.Status {
+            case Fail:
+                testCase.Failure = &junitMessage{Message: c.Message}
+                result.Failures++
+            case Skip:
+                testCase.Skipped = &junitMessage{Message: c.Message}
+                result.Skipped++
+            }
+            result.Cases = append(result.Cases, testCase)
+        }
+        if suite.ExitCode != 0 && suite.Count(Fail) <= 0 {
+            result.Cases = append(result.Cases, junitCase{
+                Name:    "exit code",
+                Failure: &junitMessage{Message: fmt.Sprintf("exit code %d", suite.ExitCode)},
+            })
+            result.Failures++
+        }
+    }
+
+    for i := range result.Cases {
+        if result.Cases[i].Name == "" {
+            result.Cases[i].Name = suite.Name
+        }
+        result.Cases[i].Classname = suite.Name
+        if result.Cases[i].Time == "" {
+            result.Cases[i].Time = junitTime(0)
+        }
+    }
+    result.Tests = len(result.Cases)
+    return result
+}
+
+// Writes the results of all the suites as a JUnit XML report
+func WriteJunit(writer io.Writer, suites []*Suite) error {
+    report := junitSuites{}
+    total := time.Duration(0)
+    for _, suite := range suites {
+        converted := toJunitSuite(suite)
+        report.Tests += converted.Tests
+        report.Failures += converted.Failures
+        report.Errors += converted.Errors
+        report.Skipped += converted.Skipped
+        report.Suites = append(report.Suites, converted)
+        total += suite.Duration
+    }
+    report.Time = junitTime(total)
+
+    if _, err := io.WriteString(writer, xml.Header); err != nil {
+        return err
+    }
+    encoder := xml.NewEncoder(writer)
+    encoder.Indent("", "  ")
+    if err := encoder.Encode(report); err != nil {
+        return err
+    }
+    _, err := io.WriteString(writer, "\n")
+    return err
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+    "bufio"
+    "bytes"
+    "io"
+    "regexp"
+    "strings"
+    "time"
+    "wio/pkg/util"
+)
+
+// Test firmware prints one of these lines once all of its tests are done.
+// OK and FAIL are the last lines printed by Unity
+const DefaultSentinel = `^(OK|FAIL|DONE)\s*$`
+
+// Reads lines from the device and copies them to out until a line matches the sentinel.
+// Returns everything read and the sentinel line. Fails if the timeout passes first
+// or the device stops sending data. The port is closed before it returns, once the
+// reader has stopped
+func ReadUntil(port io.ReadCloser, sentinel *regexp.Regexp, timeout time.Duration, out io.Writer) (string, string, error) {
+    lines := make(chan string)
+    readErr := make(chan error, 1)
+    done := make(chan struct{})
+    stopped := make(chan struct{})
+    defer func() {
+        close(done)
+        port.Close()
+        <-stopped
+    }()
+
+    go func() {
+        defer close(stopped)
+        defer close(lines)
+        scanner := bufio.NewScanner(port)
+        for scanner.Scan() {
+            select {
+            case lines <- scanner.Text():
+            case <-done:
+                return
+            }
+        }
+        readErr <- scanner.Err()
+    }()
+
+    var timer <-chan time.Time
+    if timeout > 0 {
+        timer = time.After(timeout)
+    }
+
+    buf := &bytes.Buffer{}
+    for {
+        select {
+        case line, ok := <-lines:
+            if !ok {
+                if err := <-readErr; err != nil {
+                    return buf.String(), "", err
+                }
+                return buf.String(), "", util.Error("device closed before the tests finished")
+            }
+            line = strings.TrimRight(line, "\r")
+            buf.WriteString(line + "\n")
+            if out != nil {
+                io.WriteString(out, line+"\n")
+            }
+            if sentinel.MatchString(line) {
+                return buf.String(), line, nil
+            }
+        case <-timer:
+            return buf.String(), "", util.Error("timed out after %s waiting for the tests to finish", timeout)
+        }
+    }
+}
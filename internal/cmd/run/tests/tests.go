@@ -0,0 +1,129 @@
+// Copyright 2018 Waterloop. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package tests parses the output of test executables and reports the results.
+// Both TAP and Unity style output are understood
+package tests
+
+import (
+    "regexp"
+    "strings"
+    "time"
+)
+
+const (
+    Pass = "pass"
+    Fail = "fail"
+    Skip = "skip"
+)
+
+// TAP: "ok 1 - name", "not ok 2 - name # SKIP reason"
+var tapPattern = regexp.MustCompile(`^(not )?ok\b\s*(\d+)?\s*(?:-\s*)?([^#]*?)\s*(?:#\s*(?i:(skip|todo))\b\s*(.*))?$`)
+
+// TAP version and plan lines, "TAP version 13" and "1..4", the plan may also come last
+var tapHeaderPattern = regexp.MustCompile(`^(TAP version \d+|1\.\.\d+)\b`)
+
+// TAP diagnostic line belonging to the previous test point
+var tapDiagnosticPattern = regexp.MustCompile(`^#\s?(.*)$`)
+
+// Unity: "test/main.c:23:test_name:PASS", "test/main.c:25:test_name:FAIL: Expected 1 Was 2"
+var unityPattern = regexp.MustCompile(`^(.+?):(\d+):([^:\s]+):(PASS|FAIL|IGNORE)(?::\s*(.*))?$`)
+
+// Result of a single test case
+type Case struct {
+    Name    string
+    Status  string
+    Message string
+}
+
+// Results of running one test target
+type Suite struct {
+    Name     string
+    Cases    []*Case
+    ExitCode int
+    Duration time.Duration
+    Output   string
+    // set when the target could not be built or run
+    Error string
+    // set when the target was not run at all
+    SkipReason string
+}
+
+// Parses test cases from the output of a test executable. Lines that are not
+// part of any supported protocol are ignored. So that output like "ok, starting" is not
+// taken for a test, TAP test points need a number until a version or plan line is seen
+func Parse(output string) []*Case {
+    cases := make([]*Case, 0, 16)
+    var last *Case
+    tap := false
+
+    for _, line := range strings.Split(output, "\n") {
+        line = strings.TrimRight(line, "\r")
+
+        if match := unityPattern.FindStringSubmatch(line); match != nil {
+            status := Pass
+            if match[4] == "FAIL" {
+                status = Fail
+            } else if match[4] == "IGNORE" {
+                status = Skip
+            }
+            last = &Case{Name: match[3], Status: status, Message: match[5]}
+            cases = append(cases, last)
+        } else if tapHeaderPattern.MatchString(line) {
+            tap = true
+        } else if match := tapPattern.FindStringSubmatch(line); match != nil && (tap || match[2] != "") {
+            last = &Case{Name: strings.TrimSpace(match[3]), Status: Pass}
+            if last.Name == "" {
+                last.Name = "test " + match[2]
+            }
+            if match[4] != "" {
+                // TODO tests are expected to fail and never fail the suite
+                last.Status = Skip
+                last.Message = match[5]
+            } else if match[1] != "" {
+                last.Status = Fail
+            }
+            cases = append(cases, last)
+        } else if match := tapDiagnosticPattern.FindStringSubmatch(line); match != nil && last != nil &&
+            last.Status == Fail {
+            if last.Message != "" {
+                last.Message += "\n"
+            }
+            last.Message += match[1]
+        }
+    }
+    return cases
+}
+
+// Number of cases with the given status
+func (s *Suite) Count(status string) int {
+    count := 0
+    for _, c := range s.Cases {
+        if c.Status == status {
+            count++
+        }
+    }
+    return count
+}
+
+func (s *Suite) Skipped() bool {
+    return s.SkipReason != ""
+}
+
+// A suite fails if it could not run, exits with a non zero code or has a failed case
+func (s *Suite) Failed() bool {
+    if s.Skipped() {
+        return false
+    }
+    return s.Error != "" || s.ExitCode != 0 || s.Count(Fail) > 0
+}
+
+func (s *Suite) Status() string {
+    if s.Skipped() {
+        return Skip
+    } else if s.Failed() {
+        return Fail
+    }
+    return Pass
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+    "bytes"
+    "strings"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestParse_Tap(t *testing.T) {
+    output := `TAP version 13
+1..4
+ok 1 - push back
+not ok 2 - pop front
+# expected 3
+# got 4
+ok 3 # SKIP no allocator
+ok 4 - remove # TODO not implemented
+`
+    cases := Parse(output)
+    assert.Equal(t, 4, len(cases))
+    assert.Equal(t, &Case{Name: "push back", Status: Pass}, cases[0])
+    assert.Equal(t, &Case{Name: "pop front", Status: Fail, Message: "expected 3\ngot 4"}, cases[1])
+    assert.Equal(t, &Case{Name: "test 3", Status: Skip, Message: "no allocator"}, cases[2])
+    assert.Equal(t, &Case{Name: "remove", Status: Skip, Message: "not implemented"}, cases[3])
+}
+
+func TestParse_Unity(t *testing.T) {
+    output := "tests/main.c:12:test_alloc:PASS\r\n" +
+        "tests/main.c:20:test_free:FAIL: Expected 1 Was 2\r\n" +
+        "tests/main.c:31:test_realloc:IGNORE\r\n" +
+        "\r\n-----------------------\r\n3 Tests 1 Failures 1 Ignored\r\nFAIL\r\n"
+    cases := Parse(output)
+    assert.Equal(t, 3, len(cases))
+    assert.Equal(t, &Case{Name: "test_alloc", Status: Pass}, cases[0])
+    assert.Equal(t, &Case{Name: "test_free", Status: Fail, Message: "Expected 1 Was 2"}, cases[1])
+    assert.Equal(t, &Case{Name: "test_realloc", Status: Skip}, cases[2])
+}
+
+func TestParse_Plain(t *testing.T) {
+    assert.Equal(t, 0, len(Parse("hello world\n15 7\n")))
+    assert.Equal(t, 0, len(Parse("ok, starting\nnot ok yet\nok\n")))
+}
+
+func TestParse_TapWithoutHeader(t *testing.T) {
+    // numbered test points are enough, the plan may come last
+    cases := Parse("ok, starting\nok 1 - init\nnot ok 2\n1..2\n")
+    assert.Equal(t, 2, len(cases))
+    assert.Equal(t, &Case{Name: "init", Status: Pass}, cases[0])
+    assert.Equal(t, &Case{Name: "test 2", Status: Fail}, cases[1])
+
+    // after a plan, test points may leave out their number
+    cases = Parse("1..2\nok - init\nnot ok - cleanup\n")
+    assert.Equal(t, 2, len(cases))
+    assert.Equal(t, Fail, cases[1].Status)
+}
+
+func TestSuite_Failed(t *testing.T) {
+    assert.False(t, (&Suite{}).Failed())
+    assert.True(t, (&Suite{ExitCode: 1}).Failed())
+    assert.True(t, (&Suite{Error: "build failed"}).Failed())
+    assert.True(t, (&Suite{Cases: []*Case{{Status: Pass}, {Status: Fail}}}).Failed())
+    assert.False(t, (&Suite{SkipReason: "no device", ExitCode: 1}).Failed())
+    assert.Equal(t, Skip, (&Suite{SkipReason: "no device"}).Status())
+}
+
+func TestWriteJunit(t *testing.T) {
+    suites := []*Suite{
+        {Name: "native-tests", Cases: Parse("ok 1 - a\nnot ok 2 - b\n"), ExitCode: 1},
+        {Name: "tests", ExitCode: 3},
+        {Name: "avr-tests", SkipReason: "no device"},
+        {Name: "broken", Error: "build failed"},
+    }
+    buf := &bytes.Buffer{}
+    assert.Nil(t, WriteJunit(buf, suites))
+
+    report := buf.String()
+    assert.True(t, strings.HasPrefix(report, "<?xml"))
+    assert.Contains(t, report, `<testsuites tests="5" failures="2" errors="1" skipped="1"`)
+    assert.Contains(t, report, `<testcase name="b" classname="native-tests"`)
+    assert.Contains(t, report, `<failure message="exit code 3">`)
+    assert.Contains(t, report, `<skipped message="no device">`)
+    assert.Contains(t, report, `<error message="build failed">`)
+}
@@ -0,0 +1,412 @@
+package run
+
+import (
+    "strings"
+    "time"
+    "wio/internal/cmd/devices"
+    "wio/internal/constants"
+    "wio/internal/toolchain"
+    "wio/internal/types"
+    "wio/pkg/log"
+    "wio/pkg/util"
+    "wio/pkg/util/sys"
+
+    "github.com/thoas/go-funk"
+)
+
+// Port written in dry runs when no device is connected
+const dryRunPort = "<port>"
+
+// Time boards with native usb get to show up as a bootloader after being reset
+const touchTimeout = 10 * time.Second
+
+// Interval the ports are listed at while waiting for the bootloader
+const touchPollInterval = 250 * time.Millisecond
+
+// Programmer given with --programmer or in the upload settings of the target
+func getProgrammerName(info *runInfo, target types.Target) string {
+    if info.context.IsSet("programmer") {
+        return info.context.String("programmer")
+    }
+    return target.GetUpload().GetProgrammer()
+}
+
+// Works out how to reach the device of an AVR target from the flags and its upload settings
+func getUploadMethod(info *runInfo, target types.Target) (toolchain.UploadMethod, error) {
+    method := toolchain.UploadMethod{}
+    board, err := avrBoard(target)
+    if err != nil {
+        return method, err
+    }
+    method.Board = board
+
+    if name := getProgrammerName(info, target); name != "" {
+        if method.Programmer, err = toolchain.GetProgrammer(name); err != nil {
+            return method, err
+        }
+    }
+
+    method.Speed = target.GetUpload().GetBaud()
+    if info.context.IsSet("upload-baud") {
+        method.Speed = info.context.Int("upload-baud")
+    }
+    method.Reset = target.GetUpload().GetReset()
+    if info.context.IsSet("reset") {
+        method.Reset = info.context.String("reset")
+    }
+    if err := toolchain.CheckReset(method.Reset); err != nil {
+        return method, err
+    }
+
+    method.Flags = append(method.Flags, target.GetUpload().GetFlags()...)
+    method.Flags = append(method.Flags, strings.Fields(info.context.String("avrdude-flags"))...)
+    return method, nil
+}
+
+// Ports to upload to, from --port or the upload settings of the target. Usb programmers only
+// need a port when one is given explicitly, otherwise a single empty port lets avrdude find
+// the programmer
+func uploadPorts(info *runInfo, target types.Target, method toolchain.UploadMethod) ([]string, error) {
+    board := target.GetBoard()
+    configured := target.GetUpload().GetPort()
+    if method.Programmer != nil {
+        if method.Programmer.Usb && !info.context.IsSet("port") && configured == "" {
+            return []string{""}, nil
+        }
+        // the port is the one of the programmer, which can be any board running ArduinoISP
+        board = ""
+    }
+    ports, err := getPorts(info, board, configured)
+    if err != nil && info.context.Bool("dry-run") {
+        log.Warnln("%s, using %s for the dry run", err.Error(), dryRunPort)
+        return []string{dryRunPort}, nil
+    }
+    return ports, err
+}
+
+// Fuses of the board with the ones set in the upload settings of the target taking priority
+func targetFuses(target types.Target, board *toolchain.Board) toolchain.Fuses {
+    fuses := board.Fuses
+    if value := target.GetUpload().GetFuses().GetLow(); value != "" {
+        fuses.Low = value
+    }
+    if value := target.GetUpload().GetFuses().GetHigh(); value != "" {
+        fuses.High = value
+    }
+    if value := target.GetUpload().GetFuses().GetExtended(); value != "" {
+        fuses.Extended = value
+    }
+    return fuses
+}
+
+// Image written to the device, the hex file if the build produced one and the ELF otherwise
+func firmwarePath(info *runInfo, target types.Target) string {
+    hexFile := sys.Path(binaryPath(info, target), target.GetName()+".hex")
+    if sys.Exists(hexFile) {
+        return hexFile
+    }
+    return sys.Path(binaryPath(info, target), target.GetName()+platformExtension(target.GetPlatform()))
+}
+
+func listPortNames() ([]string, error) {
+    ports, err := toolchain.GetPorts()
+    if err != nil {
+        return nil, err
+    }
+    names := make([]string, 0, len(ports.Ports))
+    for _, port := range ports.Ports {
+        names = append(names, port.Port)
+    }
+    return names, nil
+}
+
+// Boards with native usb, like the Leonardo, enter their bootloader when the port is opened at 1200 baud.
+// The bootloader is a new device that may get another name, so the port to upload to is returned
+func touchPort(method toolchain.UploadMethod) (string, error) {
+    if !method.NeedsTouch() {
+        return method.Port, nil
+    }
+    before, err := listPortNames()
+    if err != nil {
+        return "", err
+    }
+    log.Verbln("resetting %s into its bootloader", method.Port)
+    port, err := devices.OpenPort(method.Port, 1200)
+    if err != nil {
+        return "", err
+    }
+    port.Close()
+    bootloader, err := waitForBootloaderPort(method.Port, before, listPortNames, touchTimeout, touchPollInterval)
+    if err == nil && bootloader != method.Port {
+        log.Verbln("bootloader of %s is on %s", method.Port, bootloader)
+    }
+    return bootloader, err
+}
+
+// Lists the ports until one shows up that was not there before. Ports that went away are dropped
+// from the ones seen, so the board coming back under its old name is found too. If no port shows
+// up in time the original one is used as long as it still exists
+func waitForBootloaderPort(original string, before []string, listPorts func() ([]string, error),
+    timeout time.Duration, interval time.Duration) (string, error) {
+
+    deadline := time.Now().Add(timeout)
+    for {
+        time.Sleep(interval)
+        now, err := listPorts()
+        if err != nil {
+            return "", err
+        }
+        for _, port := range now {
+            if !funk.ContainsString(before, port) {
+                return port, nil
+            }
+        }
+        before = now
+        if time.Now().After(deadline) {
+            if funk.ContainsString(now, original) {
+                return original, nil
+            }
+            return "", util.Error("bootloader of %s did not show up as a serial port", original)
+        }
+    }
+}
+
+// Runs avrdude once for each set of arguments or only prints the commands in a dry run
+func runAvrdude(info *runInfo, invocations [][]string) error {
+    program, _ := toolchain.FindAvrdude()
+    if info.context.Bool("dry-run") {
+        for _, args := range invocations {
+            log.Infoln(toolchain.FormatCommand(program, args))
+        }
+        return nil
+    }
+    for _, args := range invocations {
+        log.Verbln(log.Magenta, "%s", toolchain.FormatCommand(program, args))
+        if err := Execute(info.directory, program, args...); err != nil {
+            return util.Error("avrdude failed: %s", err.Error())
+        }
+    }
+    return nil
+}
+
+// Arduino and Cosa targets on boards that avrdude is not set up for here are uploaded with
+// the rule of their build, which knows every board of the framework
+func usesUploadRule(info *runInfo, target types.Target) bool {
+    if isBaremetal(target) || getProgrammerName(info, target) != "" {
+        return false
+    }
+    _, err := toolchain.GetAvrBoard(target.GetBoard())
+    return err != nil
+}
+
+// Uploads to the port with the rule of the build or only prints the command in a dry run
+func runUploadRule(info *runInfo, target types.Target, port string) error {
+    if info.context.Bool("dry-run") {
+        log.Infoln(toolchain.FormatCommand("make", uploadTargetArgs(port)))
+        return nil
+    }
+    if err := uploadTarget(binaryPath(info, target), port); err != nil {
+        return util.Error("upload failed: %s", err.Error())
+    }
+    return nil
+}
+
+// Function that writes the firmware of the target to the device on a port
+func firmwareUploader(info *runInfo, target types.Target) (func(string) error, []string, error) {
+    if usesUploadRule(info, target) {
+        if info.context.Bool("fuses") {
+            return nil, nil, util.Error("fuses can not be set for board [%s]", target.GetBoard())
+        }
+        log.Verbln("board [%s] is not known to avrdude here, using the upload rule of the build",
+            target.GetBoard())
+        ports, err := uploadPorts(info, target, toolchain.UploadMethod{})
+        if err != nil {
+            return nil, nil, err
+        }
+        upload := func(port string) error {
+            return runUploadRule(info, target, port)
+        }
+        return upload, ports, nil
+    }
+
+    method, err := getUploadMethod(info, target)
+    if err != nil {
+        return nil, nil, err
+    }
+    ports, err := uploadPorts(info, target, method)
+    if err != nil {
+        return nil, nil, err
+    }
+    var fuses *toolchain.Fuses
+    if info.context.Bool("fuses") {
+        targetFuses := targetFuses(target, method.Board)
+        fuses = &targetFuses
+    }
+    _, config := toolchain.FindAvrdude()
+    file := firmwarePath(info, target)
+    upload := func(port string) error {
+        method.Port = port
+        if !info.context.Bool("dry-run") {
+            bootloader, err := touchPort(method)
+            if err != nil {
+                return err
+            }
+            method.Port = bootloader
+        }
+        args, err := method.UploadArgs(config, file, fuses)
+        if err != nil {
+            return err
+        }
+        return runAvrdude(info, [][]string{args})
+    }
+    return upload, ports, nil
+}
+
+// Writes the firmware of an AVR target to the device on each of the ports.
+// Every port is tried even if uploading to one of them fails
+func uploadFirmware(info *runInfo, target types.Target) error {
+    upload, ports, err := firmwareUploader(info, target)
+    if err != nil {
+        return err
+    }
+
+    var failed []string
+    for _, port := range ports {
+        if len(ports) > 1 {
+            log.Info(log.Cyan, "Uploading to ")
+            log.Infoln(log.Magenta, port)
+        }
+        if err := upload(port); err != nil {
+            if len(ports) == 1 {
+                return err
+            }
+            log.Errln("%s: %s", port, err.Error())
+            failed = append(failed, port)
+        }
+    }
+    if len(failed) > 0 {
+        return util.Error("upload failed on %d of %d ports: %s", len(failed), len(ports),
+            strings.Join(failed, ", "))
+    }
+    return nil
+}
+
+// Baud rate of the serial output of the target, from --baud or its monitor settings
+func monitorBaud(info *runInfo, target types.Target) int {
+    if baud := target.GetMonitor().GetBaud(); baud > 0 && !info.context.IsSet("baud") {
+        return baud
+    }
+    return info.context.Int("baud")
+}
+
+// Port of the serial output of the target. It is the upload port unless the monitor settings
+// name another one, e.g. for a board programmed with a programmer
+func monitorPort(info *runInfo, target types.Target) (string, error) {
+    configured := target.GetMonitor().GetPort()
+    if configured == "" {
+        configured = target.GetUpload().GetPort()
+    }
+    return getPort(info, target.GetBoard(), configured)
+}
+
+// Opens the serial monitor on the port of the board once it has been uploaded to. The monitor
+// reconnects, so it keeps running while the target is uploaded again from another terminal
+func monitorTarget(info *runInfo, target types.Target) error {
+    port, err := monitorPort(info, target)
+    if err != nil {
+        return err
+    }
+    mode, err := devices.NewMode(monitorBaud(info, target), 8, "none", "1")
+    if err != nil {
+        return err
+    }
+    ending := "lf"
+    if name := target.GetMonitor().GetLineEnding(); name != "" {
+        ending = name
+    }
+    lineEnding, err := devices.GetLineEnding(ending)
+    if err != nil {
+        return err
+    }
+    return devices.HandleMonitor(devices.MonitorOptions{
+        Port:       port,
+        Mode:       mode,
+        LineEnding: lineEnding,
+        Reconnect:  true,
+    })
+}
+
+func checkAvrTargets(targets []types.Target) error {
+    for _, target := range targets {
+        if target.GetPlatform() != constants.Avr {
+            return util.Error("target %s is not an AVR target", target.GetName())
+        }
+    }
+    return nil
+}
+
+// Builds the targets if needed and uploads them
+func (info *runInfo) upload(targets []types.Target) error {
+    if err := checkAvrTargets(targets); err != nil {
+        return err
+    }
+    for _, target := range targets {
+        log.Info(log.Cyan, "Target: ")
+        log.Infoln(log.Magenta, target.GetName())
+        if !dispatchCanRunTarget(info, target) && !info.context.Bool("dry-run") {
+            if err := info.build([]types.Target{target}); err != nil {
+                return err
+            }
+        }
+        if err := uploadFirmware(info, target); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// Erases the device, sets its fuses and writes the bootloader of the board with a programmer
+func (info *runInfo) burnBootloader(targets []types.Target) error {
+    if err := checkAvrTargets(targets); err != nil {
+        return err
+    }
+    for _, target := range targets {
+        log.Info(log.Cyan, "Target: ")
+        log.Infoln(log.Magenta, target.GetName())
+        method, err := getUploadMethod(info, target)
+        if err != nil {
+            return err
+        }
+        if method.Programmer == nil {
+            return util.Error("burning a bootloader needs a programmer, use --programmer")
+        }
+        ports, err := uploadPorts(info, target, method)
+        if err != nil {
+            return err
+        }
+        if len(ports) > 1 {
+            return util.Error("a bootloader can only be burned with one programmer at a time")
+        }
+        method.Port = ports[0]
+
+        file := info.context.String("bootloader")
+        if file == "" {
+            if file, err = toolchain.BootloaderPath(method.Board); err != nil {
+                return err
+            }
+        }
+        if !sys.Exists(file) && !info.context.Bool("dry-run") {
+            return util.Error("bootloader %s does not exist", file)
+        }
+
+        _, config := toolchain.FindAvrdude()
+        invocations, err := method.BootloaderArgs(config, file, targetFuses(target, method.Board))
+        if err != nil {
+            return err
+        }
+        if err := runAvrdude(info, invocations); err != nil {
+            return err
+        }
+    }
+    return nil
+}
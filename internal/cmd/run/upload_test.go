@@ -0,0 +1,49 @@
+package run
+
+import (
+    "errors"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+// Lists the ports of each step in turn and the last one from then on
+func portSteps(steps ...[]string) func() ([]string, error) {
+    return func() ([]string, error) {
+        ports := steps[0]
+        if len(steps) > 1 {
+            steps = steps[1:]
+        }
+        return ports, nil
+    }
+}
+
+func TestWaitForBootloaderPort(t *testing.T) {
+    tests := []struct {
+        name     string
+        steps    [][]string
+        expected string
+    }{
+        {"new name", [][]string{{"/dev/ttyS0"}, {"/dev/ttyS0", "/dev/cu.usbmodem1421"}},
+            "/dev/cu.usbmodem1421"},
+        {"same name after going away", [][]string{{"/dev/ttyS0", "/dev/ttyACM0"}, {"/dev/ttyS0"},
+            {"/dev/ttyS0", "/dev/ttyACM0"}}, "/dev/ttyACM0"},
+        {"never went away", [][]string{{"/dev/ttyS0", "/dev/ttyACM0"}}, "/dev/ttyACM0"},
+    }
+    for _, test := range tests {
+        port, err := waitForBootloaderPort("/dev/ttyACM0", []string{"/dev/ttyS0", "/dev/ttyACM0"},
+            portSteps(test.steps...), 20*time.Millisecond, time.Millisecond)
+        assert.Nil(t, err, test.name)
+        assert.Equal(t, test.expected, port, test.name)
+    }
+
+    _, err := waitForBootloaderPort("/dev/ttyACM0", []string{"/dev/ttyS0", "/dev/ttyACM0"},
+        portSteps([]string{"/dev/ttyS0"}), 20*time.Millisecond, time.Millisecond)
+    assert.NotNil(t, err)
+
+    _, err = waitForBootloaderPort("/dev/ttyACM0", nil, func() ([]string, error) {
+        return nil, errors.New("no sysfs")
+    }, 20*time.Millisecond, time.Millisecond)
+    assert.NotNil(t, err)
+}
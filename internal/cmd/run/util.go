@@ -11,8 +11,21 @@ func buildPath(info *runInfo) string {
     return cmake.BuildPath(info.directory)
 }
 
+// Separates the profile from the target in the name of its build directory. CMake does not
+// allow it in target names, so it can not collide with the directory of another target
+const profileSeparator = "@"
+
+// Build directory of the target. Each profile and each variant of native targets
+// is built in its own directory
 func targetPath(info *runInfo, target types.Target) string {
-    return sys.Path(buildPath(info), target.GetName())
+    name := target.GetName()
+    if info.profile != nil {
+        name += profileSeparator + info.profileName
+    }
+    if info.variant != nil && target.GetPlatform() == constants.Native {
+        name += "-" + info.variant.name
+    }
+    return sys.Path(buildPath(info), name)
 }
 
 func binaryPath(info *runInfo, target types.Target) string {
@@ -0,0 +1,101 @@
+package run
+
+import (
+    "sort"
+    "strings"
+    "wio/pkg/util"
+
+    "github.com/urfave/cli"
+)
+
+// Build variant of native targets. A variant is built in .wio/targets/<target>-<variant>
+// so it does not clobber the normal build of the target
+type buildVariant struct {
+    name         string
+    compileFlags []string
+    linkFlags    []string
+    coverage     bool
+}
+
+// Sanitizers supported by GCC and Clang and the short names used in variant names
+var sanitizerNames = map[string]string{
+    "address":   "asan",
+    "undefined": "ubsan",
+    "leak":      "lsan",
+    "thread":    "tsan",
+    "memory":    "msan",
+}
+
+// Sanitizers that cannot be used together in one executable
+var sanitizerConflicts = map[string][]string{
+    "thread": {"address", "leak", "memory"},
+    "memory": {"address", "leak"},
+}
+
+// Parses a comma separated list of sanitizers, dropping duplicates
+func parseSanitizers(value string) ([]string, error) {
+    sanitizers := make([]string, 0, 2)
+    seen := map[string]bool{}
+    for _, sanitizer := range strings.Split(value, ",") {
+        sanitizer = strings.ToLower(strings.TrimSpace(sanitizer))
+        if sanitizer == "" || seen[sanitizer] {
+            continue
+        }
+        if _, exists := sanitizerNames[sanitizer]; !exists {
+            supported := make([]string, 0, len(sanitizerNames))
+            for name := range sanitizerNames {
+                supported = append(supported, name)
+            }
+            sort.Strings(supported)
+            return nil, util.Error("unknown sanitizer %s, supported are: %s", sanitizer,
+                strings.Join(supported, ", "))
+        }
+        seen[sanitizer] = true
+        sanitizers = append(sanitizers, sanitizer)
+    }
+    for _, sanitizer := range sanitizers {
+        for _, conflict := range sanitizerConflicts[sanitizer] {
+            if seen[conflict] {
+                return nil, util.Error("sanitizers %s and %s cannot be used together", sanitizer, conflict)
+            }
+        }
+    }
+    return sanitizers, nil
+}
+
+// Variant selected by the command flags or nil to do a normal build
+func getVariant(context *cli.Context) (*buildVariant, error) {
+    variant := &buildVariant{}
+    names := make([]string, 0, 3)
+
+    if context.Bool("coverage") {
+        variant.coverage = true
+        variant.compileFlags = append(variant.compileFlags, "--coverage", "-O0")
+        variant.linkFlags = append(variant.linkFlags, "--coverage")
+        names = append(names, "coverage")
+    }
+
+    sanitizers, err := parseSanitizers(context.String("sanitize"))
+    if err != nil {
+        return nil, err
+    }
+    if len(sanitizers) > 0 {
+        sanitizeFlag := "-fsanitize=" + strings.Join(sanitizers, ",")
+        variant.compileFlags = append(variant.compileFlags, sanitizeFlag, "-fno-omit-frame-pointer")
+        variant.linkFlags = append(variant.linkFlags, sanitizeFlag)
+        for _, sanitizer := range sanitizers {
+            if sanitizer == "undefined" {
+                // abort on the first error so tests fail instead of only printing it
+                variant.compileFlags = append(variant.compileFlags, "-fno-sanitize-recover=undefined")
+            }
+            names = append(names, sanitizerNames[sanitizer])
+        }
+    }
+
+    if len(names) <= 0 {
+        return nil, nil
+    }
+    variant.compileFlags = append(variant.compileFlags, "-g")
+    variant.name = strings.Join(names, "-")
+    return variant, nil
+}
@@ -0,0 +1,23 @@
+package run
+
+import (
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestParseSanitizers(t *testing.T) {
+    sanitizers, err := parseSanitizers(" address, Undefined,address ")
+    assert.Nil(t, err)
+    assert.Equal(t, []string{"address", "undefined"}, sanitizers)
+
+    sanitizers, err = parseSanitizers("")
+    assert.Nil(t, err)
+    assert.Equal(t, 0, len(sanitizers))
+
+    _, err = parseSanitizers("address,bounds")
+    assert.NotNil(t, err)
+
+    _, err = parseSanitizers("thread,address")
+    assert.NotNil(t, err)
+}
@@ -1,11 +1,15 @@
 package defaults
 
-import "wio/internal/constants"
+import (
+    "time"
+    "wio/internal/constants"
+)
 
 type Defaults struct {
     Keywords []string
     Target   string
     Source   string
+    Test     bool
 }
 
 const (
@@ -14,6 +18,9 @@ const (
     AVRBoard = "uno"
     Port     = "none"
     Baud     = 9600
+
+    TestTimeout     = 2 * time.Minute
+    SimulateTimeout = 2 * time.Minute
 )
 
 var App = Defaults{
@@ -26,4 +33,5 @@ var Pkg = Defaults{
     Keywords: []string{constants.Wio, constants.Pkg},
     Target:   "tests",
     Source:   "tests",
+    Test:     true,
 }
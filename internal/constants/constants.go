@@ -1,8 +1,9 @@
 package constants
 
 const (
-    Cosa    = "cosa"
-    Arduino = "arduino"
+    Cosa      = "cosa"
+    Arduino   = "arduino"
+    Baremetal = "baremetal"
 )
 
 const (
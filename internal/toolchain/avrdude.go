@@ -0,0 +1,255 @@
+package toolchain
+
+import (
+    "fmt"
+    "os/exec"
+    "path/filepath"
+    "sort"
+    "strings"
+    "wio/pkg/util"
+    "wio/pkg/util/sys"
+)
+
+// In-system programmer supported by avrdude
+type Programmer struct {
+    // avrdude programmer id passed with -c
+    Protocol string
+    // baud rate for programmers connected over serial, 0 to use the avrdude default
+    Speed int
+    // programmers connected over usb do not need a port
+    Usb bool
+}
+
+// Programmers by the names used with --programmer and in the upload section of targets
+var programmers = map[string]*Programmer{
+    "usbasp":         {Protocol: "usbasp", Usb: true},
+    "usbtinyisp":     {Protocol: "usbtiny", Usb: true},
+    "avrispmkii":     {Protocol: "avrispmkII", Usb: true},
+    "avrisp":         {Protocol: "stk500v1", Speed: 19200},
+    "arduino-as-isp": {Protocol: "stk500v1", Speed: 19200},
+    "stk500v1":       {Protocol: "stk500v1"},
+    "stk500v2":       {Protocol: "stk500v2"},
+    "atmelice_isp":   {Protocol: "atmelice_isp", Usb: true},
+    "dragon_isp":     {Protocol: "dragon_isp", Usb: true},
+}
+
+// Returns a programmer by its name
+func GetProgrammer(name string) (*Programmer, error) {
+    programmer, exists := programmers[strings.ToLower(strings.Trim(name, " "))]
+    if !exists {
+        names := make([]string, 0, len(programmers))
+        for programmerName := range programmers {
+            names = append(names, programmerName)
+        }
+        sort.Strings(names)
+        return nil, util.Error("programmer [%s] is not known, supported are: %s", name, strings.Join(names, ", "))
+    }
+    return programmer, nil
+}
+
+// How the board is put into its bootloader before uploading: boards with native usb are
+// opened at 1200 baud, the others are reset by avrdude through DTR. With none the board
+// is expected to be in its bootloader already, e.g. after pressing its reset button
+const (
+    ResetAuto  = "auto"
+    ResetTouch = "1200bps"
+    ResetNone  = "none"
+)
+
+// Bootloader protocols for which avrdude pulses DTR when it opens the port, with the ones
+// speaking to the same bootloaders without resetting the board
+var resetFreeProtocols = map[string]string{
+    "arduino": "stk500v1",
+    "wiring":  "stk500v2",
+}
+
+// Checks a reset behavior, empty meaning auto
+func CheckReset(reset string) error {
+    switch reset {
+    case "", ResetAuto, ResetTouch, ResetNone:
+        return nil
+    }
+    return util.Error("reset [%s] is not supported, use %s, %s or %s", reset, ResetAuto, ResetTouch, ResetNone)
+}
+
+// How to reach the device: through its bootloader over serial or with a programmer
+type UploadMethod struct {
+    Board      *Board
+    Programmer *Programmer
+    Port       string
+    // baud rate replacing the one of the bootloader or programmer, 0 to keep it
+    Speed int
+    Reset string
+    // extra flags passed to avrdude before the memory operations
+    Flags []string
+}
+
+// Whether the board has to be opened at 1200 baud to enter its bootloader
+func (method UploadMethod) NeedsTouch() bool {
+    if method.Programmer != nil {
+        return false
+    }
+    switch method.Reset {
+    case ResetTouch:
+        return true
+    case ResetNone:
+        return false
+    }
+    return method.Board.Protocol == "avr109"
+}
+
+// Protocol of the bootloader, one that does not reset the board if it should not be
+func (method UploadMethod) protocol() string {
+    if method.Reset == ResetNone {
+        if protocol, exists := resetFreeProtocols[method.Board.Protocol]; exists {
+            return protocol
+        }
+    }
+    return method.Board.Protocol
+}
+
+func (method UploadMethod) speed(defaultSpeed int) int {
+    if method.Speed > 0 {
+        return method.Speed
+    }
+    return defaultSpeed
+}
+
+// Path of avrdude and of its configuration file. The ones shipped with the Arduino
+// tools next to wio are preferred, otherwise avrdude is looked up in the path
+func FindAvrdude() (string, string) {
+    if toolchainPath, err := GetToolchainPath(); err == nil {
+        tools := sys.Path(toolchainPath, "arduino", "hardware", "tools", "avr")
+        program := sys.Path(tools, "bin", "avrdude")
+        config := sys.Path(tools, "etc", "avrdude.conf")
+        if sys.Exists(program) && sys.Exists(config) {
+            return program, config
+        }
+    }
+    if program, err := exec.LookPath("avrdude"); err == nil {
+        return program, ""
+    }
+    return "avrdude", ""
+}
+
+// Path of a bootloader image shipped with the Arduino core
+func BootloaderPath(board *Board) (string, error) {
+    if board.Bootloader == "" {
+        return "", util.Error("%s has no bootloader", board.Name)
+    }
+    toolchainPath, err := GetToolchainPath()
+    if err != nil {
+        return "", err
+    }
+    return sys.Path(toolchainPath, "arduino", "hardware", "arduino", "avr", "bootloaders",
+        filepath.FromSlash(board.Bootloader)), nil
+}
+
+// Arguments common to every invocation: config, part, programmer and port
+func (method UploadMethod) baseArgs(config string) ([]string, error) {
+    args := make([]string, 0, 16)
+    if config != "" {
+        args = append(args, "-C", config)
+    }
+    args = append(args, "-p", method.Board.Mcu)
+
+    if method.Programmer != nil {
+        args = append(args, "-c", method.Programmer.Protocol)
+        if speed := method.speed(method.Programmer.Speed); speed > 0 {
+            args = append(args, "-b", fmt.Sprintf("%d", speed))
+        }
+        if method.Port != "" {
+            args = append(args, "-P", method.Port)
+        } else if method.Programmer.Usb {
+            args = append(args, "-P", "usb")
+        } else {
+            return nil, util.Error("programmer %s needs a port", method.Programmer.Protocol)
+        }
+    } else {
+        if method.Board.Protocol == "" {
+            return nil, util.Error("%s has no bootloader, upload it with a programmer", method.Board.Name)
+        }
+        if method.Port == "" {
+            return nil, util.Error("uploading through the bootloader needs a port")
+        }
+        args = append(args, "-c", method.protocol(), "-b", fmt.Sprintf("%d", method.speed(method.Board.Speed)),
+            "-P", method.Port, "-D")
+    }
+    return append(args, method.Flags...), nil
+}
+
+// Memory operation writing an image to flash, using the intel hex format
+// for .hex files and letting avrdude read anything else as ELF
+func flashOperation(file string) string {
+    format := "e"
+    if strings.EqualFold(filepath.Ext(file), ".hex") {
+        format = "i"
+    }
+    return fmt.Sprintf("flash:w:%s:%s", filepath.ToSlash(file), format)
+}
+
+func fuseOperations(fuses Fuses) []string {
+    operations := make([]string, 0, 3)
+    for _, fuse := range []struct{ memory, value string }{
+        {"efuse", fuses.Extended},
+        {"hfuse", fuses.High},
+        {"lfuse", fuses.Low},
+    } {
+        if fuse.value != "" {
+            operations = append(operations, "-U", fmt.Sprintf("%s:w:%s:m", fuse.memory, fuse.value))
+        }
+    }
+    return operations
+}
+
+// Arguments to write the firmware and, if given, the fuses
+func (method UploadMethod) UploadArgs(config string, file string, fuses *Fuses) ([]string, error) {
+    args, err := method.baseArgs(config)
+    if err != nil {
+        return nil, err
+    }
+    if fuses != nil {
+        if method.Programmer == nil {
+            return nil, util.Error("fuses can only be written with a programmer")
+        }
+        args = append(args, fuseOperations(*fuses)...)
+    }
+    return append(args, "-U", flashOperation(file)), nil
+}
+
+// Arguments of the two avrdude invocations burning a bootloader the way the Arduino IDE
+// does: the chip is erased and unlocked and the fuses set, then the bootloader is
+// written and locked
+func (method UploadMethod) BootloaderArgs(config string, file string, fuses Fuses) ([][]string, error) {
+    if method.Programmer == nil {
+        return nil, util.Error("burning a bootloader needs a programmer")
+    }
+    eraseArgs, err := method.baseArgs(config)
+    if err != nil {
+        return nil, err
+    }
+    eraseArgs = append(eraseArgs, "-e")
+    if fuses.Unlock != "" {
+        eraseArgs = append(eraseArgs, "-U", fmt.Sprintf("lock:w:%s:m", fuses.Unlock))
+    }
+    eraseArgs = append(eraseArgs, fuseOperations(fuses)...)
+
+    writeArgs, _ := method.baseArgs(config)
+    writeArgs = append(writeArgs, "-U", flashOperation(file))
+    if fuses.Lock != "" {
+        writeArgs = append(writeArgs, "-U", fmt.Sprintf("lock:w:%s:m", fuses.Lock))
+    }
+    return [][]string{eraseArgs, writeArgs}, nil
+}
+
+// Command line that can be pasted into a shell
+func FormatCommand(program string, args []string) string {
+    quoted := make([]string, 0, len(args)+1)
+    for _, arg := range append([]string{program}, args...) {
+        if arg == "" || strings.ContainsAny(arg, " \t\"'\\$") {
+            arg = "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+        }
+        quoted = append(quoted, arg)
+    }
+    return strings.Join(quoted, " ")
+}
@@ -0,0 +1,104 @@
+package toolchain
+
+import (
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestUploadArgsBootloader(t *testing.T) {
+    board, _ := GetAvrBoard("uno")
+    method := UploadMethod{Board: board, Port: "/dev/ttyACM0"}
+
+    args, err := method.UploadArgs("", "build/blink.hex", nil)
+    assert.Nil(t, err)
+    assert.Equal(t, []string{"-p", "atmega328p", "-c", "arduino", "-b", "115200", "-P", "/dev/ttyACM0", "-D",
+        "-U", "flash:w:build/blink.hex:i"}, args)
+
+    _, err = method.UploadArgs("", "build/blink.hex", &board.Fuses)
+    assert.NotNil(t, err)
+
+    method.Port = ""
+    _, err = method.UploadArgs("", "build/blink.hex", nil)
+    assert.NotNil(t, err)
+
+    // clones with an old bootloader run at another speed
+    method = UploadMethod{Board: board, Port: "/dev/ttyUSB0", Speed: 57600}
+    args, _ = method.UploadArgs("", "build/blink.hex", nil)
+    assert.Equal(t, []string{"-p", "atmega328p", "-c", "arduino", "-b", "57600", "-P", "/dev/ttyUSB0", "-D",
+        "-U", "flash:w:build/blink.hex:i"}, args)
+
+    // the board is already in its bootloader and is not reset through DTR
+    method = UploadMethod{Board: board, Port: "/dev/ttyUSB0", Reset: ResetNone}
+    args, _ = method.UploadArgs("", "build/blink.hex", nil)
+    assert.Equal(t, []string{"-p", "atmega328p", "-c", "stk500v1", "-b", "115200", "-P", "/dev/ttyUSB0", "-D",
+        "-U", "flash:w:build/blink.hex:i"}, args)
+
+    mega, _ := GetAvrBoard("mega2560")
+    method = UploadMethod{Board: mega, Port: "/dev/ttyACM0", Reset: ResetNone}
+    args, _ = method.UploadArgs("", "build/blink.hex", nil)
+    assert.Equal(t, "stk500v2", args[3])
+    method.Reset = ResetAuto
+    args, _ = method.UploadArgs("", "build/blink.hex", nil)
+    assert.Equal(t, "wiring", args[3])
+}
+
+func TestNeedsTouch(t *testing.T) {
+    uno, _ := GetAvrBoard("uno")
+    leonardo, _ := GetAvrBoard("leonardo")
+    programmer, _ := GetProgrammer("usbasp")
+    assert.False(t, UploadMethod{Board: uno}.NeedsTouch())
+    assert.True(t, UploadMethod{Board: leonardo}.NeedsTouch())
+    assert.True(t, UploadMethod{Board: uno, Reset: ResetTouch}.NeedsTouch())
+    assert.False(t, UploadMethod{Board: leonardo, Reset: ResetNone}.NeedsTouch())
+    assert.False(t, UploadMethod{Board: leonardo, Programmer: programmer}.NeedsTouch())
+
+    assert.Nil(t, CheckReset(""))
+    assert.Nil(t, CheckReset("1200bps"))
+    assert.NotNil(t, CheckReset("dtr"))
+}
+
+func TestUploadArgsProgrammer(t *testing.T) {
+    board, _ := GetAvrBoard("uno")
+    programmer, err := GetProgrammer("USBasp")
+    assert.Nil(t, err)
+    method := UploadMethod{Board: board, Programmer: programmer, Flags: []string{"-B", "10"}}
+
+    args, err := method.UploadArgs("avrdude.conf", "build/blink.elf", &Fuses{High: "0xDE", Low: "0xFF"})
+    assert.Nil(t, err)
+    assert.Equal(t, []string{"-C", "avrdude.conf", "-p", "atmega328p", "-c", "usbasp", "-P", "usb", "-B", "10",
+        "-U", "hfuse:w:0xDE:m", "-U", "lfuse:w:0xFF:m", "-U", "flash:w:build/blink.elf:e"}, args)
+
+    programmer, _ = GetProgrammer("arduino-as-isp")
+    method = UploadMethod{Board: board, Programmer: programmer}
+    _, err = method.UploadArgs("", "build/blink.hex", nil)
+    assert.NotNil(t, err)
+
+    _, err = GetProgrammer("jtag9000")
+    assert.NotNil(t, err)
+}
+
+func TestBootloaderArgs(t *testing.T) {
+    board, _ := GetAvrBoard("uno")
+    programmer, _ := GetProgrammer("arduino-as-isp")
+    method := UploadMethod{Board: board, Programmer: programmer, Port: "COM3"}
+
+    invocations, err := method.BootloaderArgs("", "optiboot.hex", board.Fuses)
+    assert.Nil(t, err)
+    assert.Equal(t, [][]string{
+        {"-p", "atmega328p", "-c", "stk500v1", "-b", "19200", "-P", "COM3", "-e", "-U", "lock:w:0x3F:m",
+            "-U", "efuse:w:0xFD:m", "-U", "hfuse:w:0xDE:m", "-U", "lfuse:w:0xFF:m"},
+        {"-p", "atmega328p", "-c", "stk500v1", "-b", "19200", "-P", "COM3", "-U", "flash:w:optiboot.hex:i",
+            "-U", "lock:w:0x0F:m"},
+    }, invocations)
+
+    method.Programmer = nil
+    _, err = method.BootloaderArgs("", "optiboot.hex", board.Fuses)
+    assert.NotNil(t, err)
+}
+
+func TestFormatCommand(t *testing.T) {
+    assert.Equal(t, `avrdude -P /dev/ttyUSB0 -U 'flash:w:my project/a.hex:i' ''`,
+        FormatCommand("avrdude", []string{"-P", "/dev/ttyUSB0", "-U", "flash:w:my project/a.hex:i", ""}))
+    assert.Equal(t, `avrdude 'it'\''s'`, FormatCommand("avrdude", []string{"it's"}))
+}
@@ -0,0 +1,177 @@
+package toolchain
+
+import (
+    "strings"
+    "wio/pkg/util"
+)
+
+// Fuse and lock bytes written when burning a bootloader, as avrdude values like "0xFF"
+type Fuses struct {
+    Low      string
+    High     string
+    Extended string
+    // lock bits written before and after the bootloader
+    Unlock string
+    Lock   string
+}
+
+// Hardware description of an AVR board. Flash is what is left for the
+// program after the bootloader and both memory sizes are in bytes
+type Board struct {
+    Name  string
+    Mcu   string
+    FCpu  int
+    Flash int64
+    Ram   int64
+    // avrdude protocol and baud rate of the bootloader, empty for boards
+    // that can only be programmed with a programmer
+    Protocol string
+    Speed    int
+    // path of the bootloader image relative to the bootloaders of the Arduino core
+    Bootloader string
+    Fuses      Fuses
+}
+
+// Boards by the names used for the board option of targets in both Arduino and Cosa
+var avrBoards = map[string]*Board{
+    "uno": {
+        Name: "Arduino Uno", Mcu: "atmega328p", FCpu: 16000000, Flash: 32256, Ram: 2048,
+        Protocol: "arduino", Speed: 115200, Bootloader: "optiboot/optiboot_atmega328.hex",
+        Fuses: Fuses{Low: "0xFF", High: "0xDE", Extended: "0xFD", Unlock: "0x3F", Lock: "0x0F"},
+    },
+    "atmega328": {
+        Name: "Arduino Duemilanove", Mcu: "atmega328p", FCpu: 16000000, Flash: 30720, Ram: 2048,
+        Protocol: "arduino", Speed: 57600, Bootloader: "atmega/ATmegaBOOT_168_atmega328.hex",
+        Fuses: Fuses{Low: "0xFF", High: "0xDA", Extended: "0xFD", Unlock: "0x3F", Lock: "0x0F"},
+    },
+    "diecimila": {
+        Name: "Arduino Diecimila", Mcu: "atmega168", FCpu: 16000000, Flash: 14336, Ram: 1024,
+        Protocol: "arduino", Speed: 19200, Bootloader: "atmega/ATmegaBOOT_168_diecimila.hex",
+        Fuses: Fuses{Low: "0xFF", High: "0xDD", Extended: "0xF8", Unlock: "0x3F", Lock: "0x0F"},
+    },
+    "atmega168": {
+        Name: "Arduino NG", Mcu: "atmega168", FCpu: 16000000, Flash: 14336, Ram: 1024,
+        Protocol: "arduino", Speed: 19200, Bootloader: "atmega/ATmegaBOOT_168_ng.hex",
+        Fuses: Fuses{Low: "0xFF", High: "0xDD", Extended: "0xF8", Unlock: "0x3F", Lock: "0x0F"},
+    },
+    "nano": {
+        Name: "Arduino Nano", Mcu: "atmega328p", FCpu: 16000000, Flash: 30720, Ram: 2048,
+        Protocol: "arduino", Speed: 57600, Bootloader: "atmega/ATmegaBOOT_168_atmega328.hex",
+        Fuses: Fuses{Low: "0xFF", High: "0xDA", Extended: "0xFD", Unlock: "0x3F", Lock: "0x0F"},
+    },
+    "nano328": {
+        Name: "Arduino Nano", Mcu: "atmega328p", FCpu: 16000000, Flash: 30720, Ram: 2048,
+        Protocol: "arduino", Speed: 57600, Bootloader: "atmega/ATmegaBOOT_168_atmega328.hex",
+        Fuses: Fuses{Low: "0xFF", High: "0xDA", Extended: "0xFD", Unlock: "0x3F", Lock: "0x0F"},
+    },
+    "nano168": {
+        Name: "Arduino Nano", Mcu: "atmega168", FCpu: 16000000, Flash: 14336, Ram: 1024,
+        Protocol: "arduino", Speed: 19200, Bootloader: "atmega/ATmegaBOOT_168_diecimila.hex",
+        Fuses: Fuses{Low: "0xFF", High: "0xDD", Extended: "0xF8", Unlock: "0x3F", Lock: "0x0F"},
+    },
+    "mini": {
+        Name: "Arduino Mini", Mcu: "atmega328p", FCpu: 16000000, Flash: 28672, Ram: 2048,
+        Protocol: "arduino", Speed: 115200, Bootloader: "optiboot/optiboot_atmega328-Mini.hex",
+        Fuses: Fuses{Low: "0xFF", High: "0xD8", Extended: "0xFD", Unlock: "0x3F", Lock: "0x0F"},
+    },
+    "mini328": {
+        Name: "Arduino Mini", Mcu: "atmega328p", FCpu: 16000000, Flash: 28672, Ram: 2048,
+        Protocol: "arduino", Speed: 115200, Bootloader: "optiboot/optiboot_atmega328-Mini.hex",
+        Fuses: Fuses{Low: "0xFF", High: "0xD8", Extended: "0xFD", Unlock: "0x3F", Lock: "0x0F"},
+    },
+    "ethernet": {
+        Name: "Arduino Ethernet", Mcu: "atmega328p", FCpu: 16000000, Flash: 32256, Ram: 2048,
+        Protocol: "arduino", Speed: 115200, Bootloader: "optiboot/optiboot_atmega328.hex",
+        Fuses: Fuses{Low: "0xFF", High: "0xDE", Extended: "0xFD", Unlock: "0x3F", Lock: "0x0F"},
+    },
+    "fio": {
+        Name: "Arduino Fio", Mcu: "atmega328p", FCpu: 8000000, Flash: 30720, Ram: 2048,
+        Protocol: "arduino", Speed: 57600, Bootloader: "atmega/ATmegaBOOT_168_atmega328_pro_8MHz.hex",
+        Fuses: Fuses{Low: "0xFF", High: "0xDA", Extended: "0xFD", Unlock: "0x3F", Lock: "0x0F"},
+    },
+    "pro": {
+        Name: "Arduino Pro", Mcu: "atmega328p", FCpu: 16000000, Flash: 30720, Ram: 2048,
+        Protocol: "arduino", Speed: 57600, Bootloader: "atmega/ATmegaBOOT_168_atmega328.hex",
+        Fuses: Fuses{Low: "0xFF", High: "0xDA", Extended: "0xFD", Unlock: "0x3F", Lock: "0x0F"},
+    },
+    "pro328": {
+        Name: "Arduino Pro", Mcu: "atmega328p", FCpu: 16000000, Flash: 30720, Ram: 2048,
+        Protocol: "arduino", Speed: 57600, Bootloader: "atmega/ATmegaBOOT_168_atmega328.hex",
+        Fuses: Fuses{Low: "0xFF", High: "0xDA", Extended: "0xFD", Unlock: "0x3F", Lock: "0x0F"},
+    },
+    "pro5v328": {
+        Name: "Arduino Pro 5V", Mcu: "atmega328p", FCpu: 16000000, Flash: 30720, Ram: 2048,
+        Protocol: "arduino", Speed: 57600, Bootloader: "atmega/ATmegaBOOT_168_atmega328.hex",
+        Fuses: Fuses{Low: "0xFF", High: "0xDA", Extended: "0xFD", Unlock: "0x3F", Lock: "0x0F"},
+    },
+    "pro-mini": {
+        Name: "Arduino Pro Mini", Mcu: "atmega328p", FCpu: 16000000, Flash: 30720, Ram: 2048,
+        Protocol: "arduino", Speed: 57600, Bootloader: "atmega/ATmegaBOOT_168_atmega328.hex",
+        Fuses: Fuses{Low: "0xFF", High: "0xDA", Extended: "0xFD", Unlock: "0x3F", Lock: "0x0F"},
+    },
+    "pro-micro": {
+        Name: "Arduino Pro Micro", Mcu: "atmega32u4", FCpu: 16000000, Flash: 28672, Ram: 2560,
+        Protocol: "avr109", Speed: 57600, Bootloader: "caterina/Caterina-promicro16.hex",
+        Fuses: Fuses{Low: "0xFF", High: "0xD8", Extended: "0xCB", Unlock: "0x3F", Lock: "0x2F"},
+    },
+    "pro8mhzatmega328": {
+        Name: "Arduino Pro Mini 3.3V", Mcu: "atmega328p", FCpu: 8000000, Flash: 30720, Ram: 2048,
+        Protocol: "arduino", Speed: 57600, Bootloader: "atmega/ATmegaBOOT_168_atmega328_pro_8MHz.hex",
+        Fuses: Fuses{Low: "0xFF", High: "0xDA", Extended: "0xFD", Unlock: "0x3F", Lock: "0x0F"},
+    },
+    "lilypad328": {
+        Name: "LilyPad Arduino", Mcu: "atmega328p", FCpu: 8000000, Flash: 30720, Ram: 2048,
+        Protocol: "arduino", Speed: 57600, Bootloader: "atmega/ATmegaBOOT_168_atmega328_pro_8MHz.hex",
+        Fuses: Fuses{Low: "0xFF", High: "0xDA", Extended: "0xFD", Unlock: "0x3F", Lock: "0x0F"},
+    },
+    "mega": {
+        Name: "Arduino Mega", Mcu: "atmega1280", FCpu: 16000000, Flash: 126976, Ram: 8192,
+        Protocol: "arduino", Speed: 57600, Bootloader: "atmega/ATmegaBOOT_168_atmega1280.hex",
+        Fuses: Fuses{Low: "0xFF", High: "0xDA", Extended: "0xF5", Unlock: "0x3F", Lock: "0x0F"},
+    },
+    "mega2560": {
+        Name: "Arduino Mega 2560", Mcu: "atmega2560", FCpu: 16000000, Flash: 253952, Ram: 8192,
+        Protocol: "wiring", Speed: 115200, Bootloader: "stk500v2/stk500boot_v2_mega2560.hex",
+        Fuses: Fuses{Low: "0xFF", High: "0xD8", Extended: "0xFD", Unlock: "0x3F", Lock: "0x0F"},
+    },
+    "leonardo": {
+        Name: "Arduino Leonardo", Mcu: "atmega32u4", FCpu: 16000000, Flash: 28672, Ram: 2560,
+        Protocol: "avr109", Speed: 57600, Bootloader: "caterina/Caterina-Leonardo.hex",
+        Fuses: Fuses{Low: "0xFF", High: "0xD8", Extended: "0xCB", Unlock: "0x3F", Lock: "0x2F"},
+    },
+    "micro": {
+        Name: "Arduino Micro", Mcu: "atmega32u4", FCpu: 16000000, Flash: 28672, Ram: 2560,
+        Protocol: "avr109", Speed: 57600, Bootloader: "caterina/Caterina-Micro.hex",
+        Fuses: Fuses{Low: "0xFF", High: "0xD8", Extended: "0xCB", Unlock: "0x3F", Lock: "0x2F"},
+    },
+    "esplora": {
+        Name: "Arduino Esplora", Mcu: "atmega32u4", FCpu: 16000000, Flash: 28672, Ram: 2560,
+        Protocol: "avr109", Speed: 57600, Bootloader: "caterina/Caterina-Esplora.hex",
+        Fuses: Fuses{Low: "0xFF", High: "0xD8", Extended: "0xCB", Unlock: "0x3F", Lock: "0x2F"},
+    },
+    "attiny84": {
+        Name: "ATtiny84", Mcu: "attiny84", FCpu: 8000000, Flash: 8192, Ram: 512,
+        Fuses: Fuses{Low: "0xE2", High: "0xDF", Extended: "0xFF"},
+    },
+    "attiny85": {
+        Name: "ATtiny85", Mcu: "attiny85", FCpu: 8000000, Flash: 8192, Ram: 512,
+        Fuses: Fuses{Low: "0xE2", High: "0xDF", Extended: "0xFF"},
+    },
+    "atmega328p": {
+        Name: "ATmega328P", Mcu: "atmega328p", FCpu: 8000000, Flash: 32768, Ram: 2048,
+        Fuses: Fuses{Low: "0xE2", High: "0xD9", Extended: "0xFF"},
+    },
+    "atmega1284p": {
+        Name: "ATmega1284P", Mcu: "atmega1284p", FCpu: 8000000, Flash: 131072, Ram: 16384,
+        Fuses: Fuses{Low: "0xE2", High: "0x99", Extended: "0xFF"},
+    },
+}
+
+// Returns the description of an AVR board by its name
+func GetAvrBoard(name string) (*Board, error) {
+    board, exists := avrBoards[strings.ToLower(strings.Trim(name, " "))]
+    if !exists {
+        return nil, util.Error("board [%s] is not known", name)
+    }
+    return board, nil
+}
@@ -4,7 +4,8 @@ import (
     "bytes"
     "encoding/json"
     "os"
-    "strings"
+    "wio/pkg/util"
+    "wio/pkg/util/sys"
 )
 
 type SerialPort struct {
@@ -14,14 +15,44 @@ type SerialPort struct {
     Manufacturer string
     SerialNumber string `json:"serial-number"`
     Vid          string
+    Pid          string
     Product      string
 }
 
+// Ports of usb devices have a vendor id, the others are built in serial ports
+func (port SerialPort) IsUsb() bool {
+    return port.Vid != ""
+}
+
 type SerialPorts struct {
     Ports []SerialPort
 }
 
+// Lists the serial ports of the machine
+type PortEnumerator interface {
+    Ports() ([]SerialPort, error)
+}
+
+// Enumerator for the operating system wio runs on
+func NewPortEnumerator() PortEnumerator {
+    if operatingSystem == sys.LINUX {
+        return sysfsEnumerator{sysRoot: "/sys", udevRoot: "/run/udev/data", devRoot: "/dev"}
+    }
+    return pySerialEnumerator{}
+}
+
 func GetPorts() (*SerialPorts, error) {
+    ports, err := NewPortEnumerator().Ports()
+    if err != nil {
+        return nil, err
+    }
+    return &SerialPorts{Ports: ports}, nil
+}
+
+// Lists ports with the pyserial binaries bundled for the operating systems without sysfs
+type pySerialEnumerator struct{}
+
+func (pySerialEnumerator) Ports() ([]SerialPort, error) {
     cmd, err := GetPySerialCommand("-get-serial-devices")
     if err != nil {
         return nil, err
@@ -30,26 +61,45 @@ func GetPorts() (*SerialPorts, error) {
     cmdOutput := &bytes.Buffer{}
     cmd.Stdout = cmdOutput
     cmd.Stderr = os.Stderr
-    cmd.Run()
+    if err := cmd.Run(); err != nil {
+        return nil, util.Error("failed to list serial ports: %s", err.Error())
+    }
 
     ports := &SerialPorts{}
-    if err := json.Unmarshal([]byte(cmdOutput.String()), ports); err != nil {
+    if err := json.Unmarshal(cmdOutput.Bytes(), ports); err != nil {
         return nil, err
     }
 
-    return ports, nil
+    // pyserial writes missing values as "None"
+    for i := range ports.Ports {
+        for _, value := range []*string{&ports.Ports[i].Manufacturer, &ports.Ports[i].SerialNumber,
+            &ports.Ports[i].Vid, &ports.Ports[i].Pid, &ports.Ports[i].Product} {
+            if *value == "None" {
+                *value = ""
+            }
+        }
+    }
+    return ports.Ports, nil
 }
 
+// Port of a connected Arduino when no board is asked for: a known board, then a device
+// calling itself an Arduino and finally a usb to serial adapter as used by clones
 func GetArduinoPort(ports *SerialPorts) *SerialPort {
-    for _, port := range ports.Ports {
-        arduinoStr := "arduino"
-
-        if strings.Contains(strings.ToLower(port.Description), arduinoStr) ||
-            strings.Contains(strings.ToLower(port.Product), arduinoStr) ||
-            strings.Contains(strings.ToLower(port.Manufacturer), arduinoStr) {
-            return &port
+    var named, bridge *SerialPort
+    for i := range ports.Ports {
+        port := &ports.Ports[i]
+        if len(DetectBoards(*port)) > 0 {
+            return port
+        }
+        if named == nil && isArduinoPort(*port) {
+            named = port
+        }
+        if bridge == nil && SerialBridge(*port) != "" {
+            bridge = port
         }
     }
-
-    return nil
+    if named != nil {
+        return named
+    }
+    return bridge
 }
@@ -0,0 +1,173 @@
+package toolchain
+
+import (
+    "bufio"
+    "bytes"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+)
+
+// Names of the tty devices that are serial ports, the same ones pyserial lists
+var serialPrefixes = []string{"ttyS", "ttyUSB", "ttyXRUSB", "ttyACM", "ttyAMA", "rfcomm", "ttyAP", "ttyGS"}
+
+// Lists serial ports on Linux from the tty class in sysfs. Details of usb devices come from
+// the attributes of the usb device and are completed with the properties udev stored for it
+type sysfsEnumerator struct {
+    sysRoot  string
+    udevRoot string
+    devRoot  string
+}
+
+func isSerialName(name string) bool {
+    for _, prefix := range serialPrefixes {
+        if strings.HasPrefix(name, prefix) {
+            return true
+        }
+    }
+    return false
+}
+
+// Value of a sysfs attribute or "" if it does not exist
+func readAttribute(path string, name string) string {
+    data, err := ioutil.ReadFile(filepath.Join(path, name))
+    if err != nil {
+        return ""
+    }
+    return strings.TrimSpace(string(data))
+}
+
+// Properties udev keeps for a device in its database, which is named after the device numbers
+func (e sysfsEnumerator) udevProperties(ttyPath string) map[string]string {
+    properties := map[string]string{}
+    numbers := readAttribute(ttyPath, "dev")
+    if numbers == "" {
+        return properties
+    }
+    file, err := os.Open(filepath.Join(e.udevRoot, "c"+numbers))
+    if err != nil {
+        return properties
+    }
+    defer file.Close()
+
+    scanner := bufio.NewScanner(file)
+    for scanner.Scan() {
+        line := scanner.Text()
+        if !strings.HasPrefix(line, "E:") {
+            continue
+        }
+        if parts := strings.SplitN(line[2:], "=", 2); len(parts) == 2 {
+            properties[parts[0]] = parts[1]
+        }
+    }
+    return properties
+}
+
+// Undoes the \xNN escapes udev uses for spaces and other characters in the _ENC properties
+func unescapeUdev(value string) string {
+    var result bytes.Buffer
+    for i := 0; i < len(value); i++ {
+        if value[i] == '\\' && i+3 < len(value) && value[i+1] == 'x' {
+            if char, err := strconv.ParseUint(value[i+2:i+4], 16, 8); err == nil {
+                result.WriteByte(byte(char))
+                i += 3
+                continue
+            }
+        }
+        result.WriteByte(value[i])
+    }
+    return result.String()
+}
+
+// Sets the value from the first of the udev properties that exists if it is still empty
+func fillFromUdev(value *string, properties map[string]string, keys ...string) {
+    for _, key := range keys {
+        if *value == "" {
+            *value = unescapeUdev(properties[key])
+        }
+    }
+}
+
+// Details of a serial port or nil if the tty is not backed by a device
+func (e sysfsEnumerator) readPort(name string) *SerialPort {
+    ttyPath := filepath.Join(e.sysRoot, "class", "tty", name)
+    devicePath, err := filepath.EvalSymlinks(filepath.Join(ttyPath, "device"))
+    if err != nil {
+        // virtual terminals have no device
+        return nil
+    }
+    subsystem, err := filepath.EvalSymlinks(filepath.Join(devicePath, "subsystem"))
+    if err != nil {
+        return nil
+    }
+
+    // ports of the platform serial driver are listed whether or not the hardware exists
+    var interfacePath string
+    switch filepath.Base(subsystem) {
+    case "platform":
+        return nil
+    case "usb-serial":
+        interfacePath = filepath.Dir(devicePath)
+    case "usb":
+        interfacePath = devicePath
+    }
+
+    port := &SerialPort{Port: filepath.Join(e.devRoot, name), Description: "n/a", Hwid: "n/a"}
+    var location string
+    if interfacePath != "" {
+        usbPath := filepath.Dir(interfacePath)
+        port.Vid = readAttribute(usbPath, "idVendor")
+        port.Pid = readAttribute(usbPath, "idProduct")
+        port.SerialNumber = readAttribute(usbPath, "serial")
+        port.Manufacturer = readAttribute(usbPath, "manufacturer")
+        port.Product = readAttribute(usbPath, "product")
+        location = filepath.Base(interfacePath)
+    }
+
+    properties := e.udevProperties(ttyPath)
+    fillFromUdev(&port.Vid, properties, "ID_VENDOR_ID")
+    fillFromUdev(&port.Pid, properties, "ID_MODEL_ID")
+    fillFromUdev(&port.SerialNumber, properties, "ID_SERIAL_SHORT")
+    fillFromUdev(&port.Manufacturer, properties, "ID_VENDOR_FROM_DATABASE", "ID_VENDOR_ENC")
+    fillFromUdev(&port.Product, properties, "ID_MODEL_FROM_DATABASE", "ID_MODEL_ENC")
+    port.Vid = strings.ToLower(port.Vid)
+    port.Pid = strings.ToLower(port.Pid)
+
+    if port.IsUsb() {
+        port.Hwid = fmt.Sprintf("USB VID:PID=%s:%s", strings.ToUpper(port.Vid), strings.ToUpper(port.Pid))
+        if port.SerialNumber != "" {
+            port.Hwid += " SER=" + port.SerialNumber
+        }
+        if location != "" {
+            port.Hwid += " LOCATION=" + location
+        }
+        port.Description = name
+        if port.Product != "" {
+            port.Description = port.Product
+        }
+    }
+    return port
+}
+
+func (e sysfsEnumerator) Ports() ([]SerialPort, error) {
+    entries, err := ioutil.ReadDir(filepath.Join(e.sysRoot, "class", "tty"))
+    if os.IsNotExist(err) {
+        return []SerialPort{}, nil
+    } else if err != nil {
+        return nil, err
+    }
+
+    ports := make([]SerialPort, 0, len(entries))
+    for _, entry := range entries {
+        if !isSerialName(entry.Name()) {
+            continue
+        }
+        if port := e.readPort(entry.Name()); port != nil {
+            ports = append(ports, *port)
+        }
+    }
+    return ports, nil
+}
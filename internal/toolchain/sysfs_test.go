@@ -0,0 +1,121 @@
+package toolchain
+
+import (
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+type fakeSysfs struct {
+    t    *testing.T
+    root string
+}
+
+func (f fakeSysfs) file(path string, content string) {
+    path = filepath.Join(f.root, path)
+    assert.Nil(f.t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+    assert.Nil(f.t, ioutil.WriteFile(path, []byte(content+"\n"), os.ModePerm))
+}
+
+// Links path to target, both relative to the root, the way sysfs links devices
+func (f fakeSysfs) link(path string, target string) {
+    path = filepath.Join(f.root, path)
+    target = filepath.Join(f.root, target)
+    assert.Nil(f.t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+    assert.Nil(f.t, os.MkdirAll(target, os.ModePerm))
+    relative, err := filepath.Rel(filepath.Dir(path), target)
+    assert.Nil(f.t, err)
+    assert.Nil(f.t, os.Symlink(relative, path))
+}
+
+// Tree with an Arduino Uno, a CH340 clone, a platform port, a pnp port and a virtual terminal
+func newFakeSysfs(t *testing.T) fakeSysfs {
+    root, err := ioutil.TempDir("", "wio-sysfs")
+    assert.Nil(t, err)
+    f := fakeSysfs{t: t, root: root}
+    usb := "sys/devices/pci0000:00/0000:00:14.0/usb1"
+
+    // cdc-acm: the tty device is the usb interface
+    f.file(usb+"/1-2/idVendor", "2341")
+    f.file(usb+"/1-2/idProduct", "0043")
+    f.file(usb+"/1-2/serial", "75833353035351A0D0A1")
+    f.file(usb+"/1-2/manufacturer", "Arduino (www.arduino.cc)")
+    f.link(usb+"/1-2/1-2:1.0/subsystem", "sys/bus/usb")
+    f.file(usb+"/1-2/1-2:1.0/tty/ttyACM0/dev", "166:0")
+    f.link(usb+"/1-2/1-2:1.0/tty/ttyACM0/device", usb+"/1-2/1-2:1.0")
+    f.link("sys/class/tty/ttyACM0", usb+"/1-2/1-2:1.0/tty/ttyACM0")
+
+    // usb-serial: the tty device hangs off the usb interface
+    f.file(usb+"/1-3/idVendor", "1A86")
+    f.file(usb+"/1-3/idProduct", "7523")
+    f.file(usb+"/1-3/product", "USB2.0-Serial")
+    f.link(usb+"/1-3/1-3:1.0/ttyUSB0/subsystem", "sys/bus/usb-serial")
+    f.file(usb+"/1-3/1-3:1.0/ttyUSB0/tty/ttyUSB0/dev", "188:0")
+    f.link(usb+"/1-3/1-3:1.0/ttyUSB0/tty/ttyUSB0/device", usb+"/1-3/1-3:1.0/ttyUSB0")
+    f.link("sys/class/tty/ttyUSB0", usb+"/1-3/1-3:1.0/ttyUSB0/tty/ttyUSB0")
+    f.file("run/udev/data/c188:0", "I:1234\nE:ID_VENDOR_FROM_DATABASE=QinHeng Electronics\n"+
+        "E:ID_MODEL_ENC=USB2.0-Serial\\x20Adapter\nE:ID_SERIAL_SHORT=A_1\nG:systemd")
+
+    f.link("sys/devices/platform/serial8250/subsystem", "sys/bus/platform")
+    f.link("sys/devices/platform/serial8250/tty/ttyS1/device", "sys/devices/platform/serial8250")
+    f.link("sys/class/tty/ttyS1", "sys/devices/platform/serial8250/tty/ttyS1")
+
+    f.link("sys/devices/pnp0/00:05/subsystem", "sys/bus/pnp")
+    f.link("sys/devices/pnp0/00:05/tty/ttyS0/device", "sys/devices/pnp0/00:05")
+    f.link("sys/class/tty/ttyS0", "sys/devices/pnp0/00:05/tty/ttyS0")
+
+    f.link("sys/class/tty/tty1", "sys/devices/virtual/tty/tty1")
+    f.link("sys/class/tty/ttyS9", "sys/devices/virtual/tty/ttyS9")
+    return f
+}
+
+func TestSysfsPorts(t *testing.T) {
+    f := newFakeSysfs(t)
+    defer os.RemoveAll(f.root)
+
+    enumerator := sysfsEnumerator{
+        sysRoot:  filepath.Join(f.root, "sys"),
+        udevRoot: filepath.Join(f.root, "run", "udev", "data"),
+        devRoot:  "/dev",
+    }
+    ports, err := enumerator.Ports()
+    assert.Nil(t, err)
+    assert.Equal(t, []SerialPort{
+        {
+            Port:         "/dev/ttyACM0",
+            Description:  "ttyACM0",
+            Hwid:         "USB VID:PID=2341:0043 SER=75833353035351A0D0A1 LOCATION=1-2:1.0",
+            Manufacturer: "Arduino (www.arduino.cc)",
+            SerialNumber: "75833353035351A0D0A1",
+            Vid:          "2341",
+            Pid:          "0043",
+        },
+        {
+            Port:        "/dev/ttyS0",
+            Description: "n/a",
+            Hwid:        "n/a",
+        },
+        {
+            Port:         "/dev/ttyUSB0",
+            Description:  "USB2.0-Serial",
+            Hwid:         "USB VID:PID=1A86:7523 SER=A_1 LOCATION=1-3:1.0",
+            Manufacturer: "QinHeng Electronics",
+            SerialNumber: "A_1",
+            Vid:          "1a86",
+            Pid:          "7523",
+            Product:      "USB2.0-Serial",
+        },
+    }, ports)
+
+    ports, err = sysfsEnumerator{sysRoot: filepath.Join(f.root, "missing")}.Ports()
+    assert.Nil(t, err)
+    assert.Equal(t, 0, len(ports))
+}
+
+func TestUnescapeUdev(t *testing.T) {
+    assert.Equal(t, "USB2.0-Serial Adapter", unescapeUdev("USB2.0-Serial\\x20Adapter"))
+    assert.Equal(t, "a\\xZZ\\x2", unescapeUdev("a\\xZZ\\x2"))
+}
@@ -8,7 +8,6 @@ import (
 )
 
 const (
-    serialLinux   = "serial/serial-ports-linux"
     serialDarwin  = "serial/serial-ports-mac"
     serialWindows = "serial/serial-ports.exe"
 )
@@ -34,16 +33,14 @@ func GetToolchainPath() (string, error) {
     return toolchainPath, nil
 }
 
-// This is the command to execute PySerial to get ports information
+// This is the command to execute PySerial to get ports information on macOS and Windows
 func GetPySerialCommand(args ...string) (*exec.Cmd, error) {
     pySerialPath, err := GetToolchainPath()
     if err != nil {
         return nil, err
     }
 
-    if operatingSystem == sys.LINUX {
-        pySerialPath += sys.Sep + serialLinux
-    } else if operatingSystem == sys.DARWIN {
+    if operatingSystem == sys.DARWIN {
         pySerialPath += sys.Sep + serialDarwin
     } else if operatingSystem == sys.WINDOWS {
         pySerialPath += sys.Sep + serialWindows
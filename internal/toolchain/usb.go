@@ -0,0 +1,218 @@
+package toolchain
+
+import (
+    "bufio"
+    "io"
+    "os"
+    "sort"
+    "strings"
+    "sync"
+    "wio/pkg/util/sys"
+)
+
+// Vendor and product id of a usb device as lowercase hex, e.g. "2341" and "0043"
+type UsbId struct {
+    Vid string
+    Pid string
+}
+
+func newUsbId(vid string, pid string) UsbId {
+    trim := func(id string) string {
+        return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(id)), "0x")
+    }
+    return UsbId{Vid: trim(vid), Pid: trim(pid)}
+}
+
+// Usb ids of the boards with their own usb chip, from the boards.txt of the Arduino AVR core
+// 1.8.5 and the SparkFun boards. Ids of the bootloader and of the sketch are both listed
+var boardUsbIds = map[string][]UsbId{
+    "uno": {
+        {"2341", "0043"}, {"2341", "0001"}, {"2a03", "0043"}, {"2341", "0243"},
+    },
+    "mega": {
+        {"2341", "0010"}, {"2341", "0042"}, {"2a03", "0010"}, {"2a03", "0042"}, {"2341", "0210"}, {"2341", "0242"},
+    },
+    "leonardo": {
+        {"2341", "0036"}, {"2341", "8036"}, {"2a03", "0036"}, {"2a03", "8036"},
+    },
+    "micro": {
+        {"2341", "0037"}, {"2341", "8037"}, {"2a03", "0037"}, {"2a03", "8037"}, {"2341", "0237"}, {"2341", "8237"},
+    },
+    "esplora": {
+        {"2341", "003c"}, {"2341", "803c"}, {"2a03", "003c"}, {"2a03", "803c"},
+    },
+    "pro-micro": {
+        {"1b4f", "9205"}, {"1b4f", "9206"},
+    },
+}
+
+// Boards that share the ids of a board in boards.txt
+var boardIdAliases = map[string][]string{
+    "mega": {"mega2560"},
+}
+
+// Usb to serial adapters used by boards without native usb, like the Nano and Pro Mini, and by clones
+var serialBridges = map[UsbId]string{
+    {"0403", "6001"}: "FTDI FT232R",
+    {"0403", "6015"}: "FTDI FT231X",
+    {"1a86", "7523"}: "CH340",
+    {"1a86", "5523"}: "CH341",
+    {"10c4", "ea60"}: "CP210x",
+    {"067b", "2303"}: "PL2303",
+}
+
+var usbBoards map[UsbId][]string
+var usbBoardsOnce sync.Once
+
+// Reads the usb ids of the boards from a boards.txt file, which has lines like "uno.vid.0=0x2341"
+func ParseBoardsTxt(reader io.Reader) map[string][]UsbId {
+    type key struct{ board, index string }
+    vids := map[key]string{}
+    pids := map[key]string{}
+    var keys []key
+
+    scanner := bufio.NewScanner(reader)
+    for scanner.Scan() {
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        parts := strings.SplitN(line, "=", 2)
+        if len(parts) != 2 {
+            continue
+        }
+        fields := strings.Split(parts[0], ".")
+        if len(fields) != 3 || (fields[1] != "vid" && fields[1] != "pid") {
+            continue
+        }
+        k := key{fields[0], fields[2]}
+        if fields[1] == "vid" {
+            vids[k] = parts[1]
+            keys = append(keys, k)
+        } else {
+            pids[k] = parts[1]
+        }
+    }
+
+    boards := map[string][]UsbId{}
+    for _, k := range keys {
+        if pid, exists := pids[k]; exists {
+            boards[k.board] = append(boards[k.board], newUsbId(vids[k], pid))
+        }
+    }
+    return boards
+}
+
+func addBoardIds(boards map[UsbId][]string, name string, ids []UsbId) {
+    names := append([]string{name}, boardIdAliases[name]...)
+    for _, id := range ids {
+        for _, boardName := range names {
+            if _, known := avrBoards[boardName]; !known {
+                continue
+            }
+            exists := false
+            for _, existing := range boards[id] {
+                exists = exists || existing == boardName
+            }
+            if !exists {
+                boards[id] = append(boards[id], boardName)
+            }
+        }
+    }
+}
+
+// Boards by usb id, from the table above and the boards.txt of the Arduino core shipped with wio
+func getUsbBoards() map[UsbId][]string {
+    usbBoardsOnce.Do(func() {
+        usbBoards = map[UsbId][]string{}
+        for name, ids := range boardUsbIds {
+            addBoardIds(usbBoards, name, ids)
+        }
+        toolchainPath, err := GetToolchainPath()
+        if err != nil {
+            return
+        }
+        file, err := os.Open(sys.Path(toolchainPath, "arduino", "hardware", "arduino", "avr", "boards.txt"))
+        if err != nil {
+            return
+        }
+        defer file.Close()
+        for name, ids := range ParseBoardsTxt(file) {
+            addBoardIds(usbBoards, name, ids)
+        }
+    })
+    return usbBoards
+}
+
+// Names of the boards the device on the port can be, empty if it is not a known board
+func DetectBoards(port SerialPort) []string {
+    names := append([]string{}, getUsbBoards()[newUsbId(port.Vid, port.Pid)]...)
+    sort.Strings(names)
+    return names
+}
+
+// Name of the usb to serial adapter of the device on the port, empty if it is not one
+func SerialBridge(port SerialPort) string {
+    return serialBridges[newUsbId(port.Vid, port.Pid)]
+}
+
+// How well the device on a port fits a board
+type PortMatch int
+
+const (
+    // nothing is known about the device
+    UnknownDevice PortMatch = iota
+    // the device is a board other than the one asked for
+    OtherBoard
+    // the device is a usb to serial adapter, as used by clones and boards without native usb
+    BridgeDevice
+    // the usb ids are the ones of the board
+    SameBoard
+)
+
+// Compares the device on a port with a board
+func MatchBoard(port SerialPort, board string) PortMatch {
+    board = strings.ToLower(strings.TrimSpace(board))
+    detected := DetectBoards(port)
+    for _, name := range detected {
+        if name == board {
+            return SameBoard
+        }
+    }
+    if len(detected) > 0 {
+        return OtherBoard
+    }
+    if SerialBridge(port) != "" {
+        // boards with native usb show up with their own ids
+        if avrBoard, known := avrBoards[board]; known && avrBoard.Protocol == "avr109" {
+            return OtherBoard
+        }
+        return BridgeDevice
+    }
+    return UnknownDevice
+}
+
+func isArduinoPort(port SerialPort) bool {
+    arduinoStr := "arduino"
+    return strings.Contains(strings.ToLower(port.Description), arduinoStr) ||
+        strings.Contains(strings.ToLower(port.Product), arduinoStr) ||
+        strings.Contains(strings.ToLower(port.Manufacturer), arduinoStr)
+}
+
+// Port that fits the board best and how well it does. Devices that are not known
+// are only picked if they call themselves an Arduino
+func GetBoardPort(ports *SerialPorts, board string) (*SerialPort, PortMatch) {
+    var best *SerialPort
+    bestMatch := UnknownDevice
+    for i := range ports.Ports {
+        match := MatchBoard(ports.Ports[i], board)
+        if match == UnknownDevice && !isArduinoPort(ports.Ports[i]) {
+            continue
+        }
+        if best == nil || match > bestMatch {
+            best = &ports.Ports[i]
+            bestMatch = match
+        }
+    }
+    return best, bestMatch
+}
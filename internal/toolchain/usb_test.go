@@ -0,0 +1,73 @@
+package toolchain
+
+import (
+    "strings"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+const boardsTxt = `
+# Arduino AVR Core and platform.
+uno.name=Arduino/Genuino Uno
+uno.vid.0=0x2341
+uno.pid.0=0x0043
+uno.vid.1=0x2A03
+uno.pid.1=0x0043
+uno.upload.tool=avrdude
+
+mega.vid.0=0x2341
+mega.pid.0=0x0010
+yun.vid.0=0x2341
+nano.name=Arduino Nano
+`
+
+func TestParseBoardsTxt(t *testing.T) {
+    boards := ParseBoardsTxt(strings.NewReader(boardsTxt))
+    assert.Equal(t, map[string][]UsbId{
+        "uno":  {{"2341", "0043"}, {"2a03", "0043"}},
+        "mega": {{"2341", "0010"}},
+    }, boards)
+}
+
+var (
+    unoPort      = SerialPort{Port: "/dev/ttyACM0", Vid: "2341", Pid: "0043"}
+    megaPort     = SerialPort{Port: "/dev/ttyACM1", Vid: "2341", Pid: "0042"}
+    clonePort    = SerialPort{Port: "/dev/ttyUSB0", Vid: "1a86", Pid: "7523"}
+    namedPort    = SerialPort{Port: "/dev/ttyUSB1", Vid: "0000", Pid: "0001", Manufacturer: "Arduino LLC"}
+    platformPort = SerialPort{Port: "/dev/ttyS0", Description: "n/a"}
+)
+
+func TestMatchBoard(t *testing.T) {
+    assert.Equal(t, []string{"mega", "mega2560"}, DetectBoards(megaPort))
+    assert.Equal(t, SameBoard, MatchBoard(unoPort, "Uno"))
+    assert.Equal(t, SameBoard, MatchBoard(megaPort, "mega2560"))
+    assert.Equal(t, OtherBoard, MatchBoard(megaPort, "uno"))
+    assert.Equal(t, BridgeDevice, MatchBoard(clonePort, "nano328"))
+    assert.Equal(t, OtherBoard, MatchBoard(clonePort, "leonardo"))
+    assert.Equal(t, UnknownDevice, MatchBoard(platformPort, "uno"))
+}
+
+func TestGetBoardPort(t *testing.T) {
+    ports := &SerialPorts{Ports: []SerialPort{platformPort, namedPort, clonePort, megaPort, unoPort}}
+
+    port, match := GetBoardPort(ports, "uno")
+    assert.Equal(t, "/dev/ttyACM0", port.Port)
+    assert.Equal(t, SameBoard, match)
+
+    port, match = GetBoardPort(ports, "nano")
+    assert.Equal(t, "/dev/ttyUSB0", port.Port)
+    assert.Equal(t, BridgeDevice, match)
+
+    port, match = GetBoardPort(&SerialPorts{Ports: []SerialPort{platformPort, megaPort}}, "uno")
+    assert.Equal(t, "/dev/ttyACM1", port.Port)
+    assert.Equal(t, OtherBoard, match)
+
+    port, _ = GetBoardPort(&SerialPorts{Ports: []SerialPort{platformPort}}, "uno")
+    assert.Nil(t, port)
+
+    assert.Equal(t, "/dev/ttyACM1", GetArduinoPort(ports).Port)
+    assert.Equal(t, "/dev/ttyUSB1",
+        GetArduinoPort(&SerialPorts{Ports: []SerialPort{clonePort, namedPort}}).Port)
+    assert.Equal(t, "/dev/ttyUSB0", GetArduinoPort(&SerialPorts{Ports: []SerialPort{clonePort}}).Port)
+}
@@ -27,11 +27,141 @@ func (p *PropertiesImpl) GetPackage() []string {
     return p.Package
 }
 
+type SizeBudgetImpl struct {
+    Flash string `yaml:"flash,omitempty"`
+    Ram   string `yaml:"ram,omitempty"`
+}
+
+func (b *SizeBudgetImpl) GetFlash() string {
+    if b == nil {
+        return ""
+    }
+    return b.Flash
+}
+
+func (b *SizeBudgetImpl) GetRam() string {
+    if b == nil {
+        return ""
+    }
+    return b.Ram
+}
+
+type FusesImpl struct {
+    Low      string `yaml:"low,omitempty"`
+    High     string `yaml:"high,omitempty"`
+    Extended string `yaml:"extended,omitempty"`
+}
+
+func (f *FusesImpl) GetLow() string {
+    if f == nil {
+        return ""
+    }
+    return f.Low
+}
+
+func (f *FusesImpl) GetHigh() string {
+    if f == nil {
+        return ""
+    }
+    return f.High
+}
+
+func (f *FusesImpl) GetExtended() string {
+    if f == nil {
+        return ""
+    }
+    return f.Extended
+}
+
+type UploadImpl struct {
+    Port       string     `yaml:"port,omitempty"`
+    Baud       int        `yaml:"baud,omitempty"`
+    Programmer string     `yaml:"programmer,omitempty"`
+    Reset      string     `yaml:"reset,omitempty"`
+    Flags      []string   `yaml:"avrdude_flags,omitempty"`
+    Fuses      *FusesImpl `yaml:"fuses,omitempty"`
+}
+
+func (u *UploadImpl) GetPort() string {
+    if u == nil {
+        return ""
+    }
+    return u.Port
+}
+
+func (u *UploadImpl) GetBaud() int {
+    if u == nil {
+        return 0
+    }
+    return u.Baud
+}
+
+func (u *UploadImpl) GetReset() string {
+    if u == nil {
+        return ""
+    }
+    return u.Reset
+}
+
+func (u *UploadImpl) GetProgrammer() string {
+    if u == nil {
+        return ""
+    }
+    return u.Programmer
+}
+
+func (u *UploadImpl) GetFlags() []string {
+    if u == nil {
+        return []string{}
+    }
+    return u.Flags
+}
+
+func (u *UploadImpl) GetFuses() Fuses {
+    if u == nil {
+        return (*FusesImpl)(nil)
+    }
+    return u.Fuses
+}
+
+type MonitorImpl struct {
+    Port       string `yaml:"port,omitempty"`
+    Baud       int    `yaml:"baud,omitempty"`
+    LineEnding string `yaml:"line_ending,omitempty"`
+}
+
+func (m *MonitorImpl) GetPort() string {
+    if m == nil {
+        return ""
+    }
+    return m.Port
+}
+
+func (m *MonitorImpl) GetBaud() int {
+    if m == nil {
+        return 0
+    }
+    return m.Baud
+}
+
+func (m *MonitorImpl) GetLineEnding() string {
+    if m == nil {
+        return ""
+    }
+    return m.LineEnding
+}
+
 type TargetImpl struct {
     Source      string          `yaml:"src"`
     Platform    string          `yaml:"platform,omitempty"`
     Framework   string          `yaml:"framework,omitempty"`
     Board       string          `yaml:"board,omitempty"`
+    Mcu         string          `yaml:"mcu,omitempty"`
+    FCpu        int             `yaml:"f_cpu,omitempty"`
+    Test        bool            `yaml:"test,omitempty"`
+    SizeBudget  *SizeBudgetImpl `yaml:"size_budget,omitempty"`
+    Upload      *UploadImpl     `yaml:"upload,omitempty"`
+    Monitor     *MonitorImpl    `yaml:"monitor,omitempty"`
     Flags       *PropertiesImpl `yaml:"flags,omitempty"`
     Definitions *PropertiesImpl `yaml:"definitions,omitempty"`
 
@@ -66,6 +196,39 @@ func (t *TargetImpl) GetBoard() string {
     return t.Board
 }
 
+func (t *TargetImpl) GetMcu() string {
+    if t == nil {
+        return ""
+    }
+    return t.Mcu
+}
+
+func (t *TargetImpl) GetFCpu() int {
+    if t == nil {
+        return 0
+    }
+    return t.FCpu
+}
+
+func (t *TargetImpl) IsTest() bool {
+    if t == nil {
+        return false
+    }
+    return t.Test
+}
+
+func (t *TargetImpl) GetSizeBudget() SizeBudget {
+    return t.SizeBudget
+}
+
+func (t *TargetImpl) GetUpload() Upload {
+    return t.Upload
+}
+
+func (t *TargetImpl) GetMonitor() Monitor {
+    return t.Monitor
+}
+
 func (t *TargetImpl) GetFlags() Properties {
     return t.Flags
 }
@@ -263,10 +426,38 @@ func (i *InfoImpl) GetDefinitions() Definitions {
     return i.Definitions
 }
 
+type ProfileImpl struct {
+    BuildType   string   `yaml:"build_type,omitempty"`
+    Flags       []string `yaml:"flags,omitempty"`
+    Definitions []string `yaml:"definitions,omitempty"`
+}
+
+func (p *ProfileImpl) GetBuildType() string {
+    if p == nil {
+        return ""
+    }
+    return p.BuildType
+}
+
+func (p *ProfileImpl) GetFlags() []string {
+    if p == nil {
+        return []string{}
+    }
+    return p.Flags
+}
+
+func (p *ProfileImpl) GetDefinitions() []string {
+    if p == nil {
+        return []string{}
+    }
+    return p.Definitions
+}
+
 type ConfigImpl struct {
     Type         string                     `yaml:"type"`
     Info         *InfoImpl                  `yaml:"project"`
     Targets      map[string]*TargetImpl     `yaml:"targets"`
+    Profiles     map[string]*ProfileImpl    `yaml:"profiles,omitempty"`
     Dependencies map[string]*DependencyImpl `yaml:"dependencies,omitempty"`
 }
 
@@ -297,6 +488,14 @@ func (c *ConfigImpl) GetTargets() map[string]Target {
     return s
 }
 
+func (c *ConfigImpl) GetProfiles() map[string]Profile {
+    s := map[string]Profile{}
+    for name, value := range c.Profiles {
+        s[name] = value
+    }
+    return s
+}
+
 func (c *ConfigImpl) GetDependencies() map[string]Dependency {
     if c.Dependencies == nil {
         c.Dependencies = map[string]*DependencyImpl{}
@@ -12,11 +12,43 @@ type Properties interface {
     GetPackage() []string
 }
 
+type SizeBudget interface {
+    GetFlash() string
+    GetRam() string
+}
+
+type Fuses interface {
+    GetLow() string
+    GetHigh() string
+    GetExtended() string
+}
+
+type Upload interface {
+    GetPort() string
+    GetBaud() int
+    GetProgrammer() string
+    GetReset() string
+    GetFlags() []string
+    GetFuses() Fuses
+}
+
+type Monitor interface {
+    GetPort() string
+    GetBaud() int
+    GetLineEnding() string
+}
+
 type Target interface {
     GetSource() string
     GetPlatform() string
     GetFramework() string
     GetBoard() string
+    GetMcu() string
+    GetFCpu() int
+    IsTest() bool
+    GetSizeBudget() SizeBudget
+    GetUpload() Upload
+    GetMonitor() Monitor
     GetFlags() Properties
     GetDefinitions() Properties
 
@@ -70,6 +102,12 @@ type Info interface {
     GetDefinitions() Definitions
 }
 
+type Profile interface {
+    GetBuildType() string
+    GetFlags() []string
+    GetDefinitions() []string
+}
+
 type Config interface {
     GetType() string
     GetName() string
@@ -77,6 +115,7 @@ type Config interface {
 
     GetInfo() Info
     GetTargets() map[string]Target
+    GetProfiles() map[string]Profile
     GetDependencies() map[string]Dependency
 
     AddDependency(name string, dep Dependency)
@@ -9,6 +9,7 @@ package log
 import (
     "bufio"
     "fmt"
+    "io"
     "os"
     "strings"
     "wio/pkg/util"
@@ -70,6 +71,13 @@ func SetVerbose() {
     createdWriter.verbose = true
 }
 
+// Sends the logs to the given writers instead of stdout and stderr and returns the previous ones
+func SetOutput(out io.Writer, err io.Writer) (io.Writer, io.Writer) {
+    prevOut, prevErr := logOut, logErr
+    logOut, logErr = out, err
+    return prevOut, prevErr
+}
+
 // Disable all the warning shown by wio
 func DisableWarnings() {
     createdWriter.warnings = false
@@ -0,0 +1,63 @@
+package log
+
+import (
+    "bytes"
+    "strings"
+    "sync"
+
+    "github.com/fatih/color"
+)
+
+// PrefixWriter logs every complete line written to it with a colored prefix in front.
+// This is used to tell apart the output of processes running at the same time
+type PrefixWriter struct {
+    prefix string
+    color  *color.Color
+    level  Type
+    buffer []byte
+    lock   sync.Mutex
+}
+
+// Creates a writer that logs lines at the given level with the prefix in front of them
+func NewPrefixWriter(prefix string, prefixColor *color.Color, level Type) *PrefixWriter {
+    if prefixColor == nil {
+        prefixColor = Default
+    }
+    return &PrefixWriter{
+        prefix: prefix,
+        color:  prefixColor,
+        level:  level,
+    }
+}
+
+func (w *PrefixWriter) Write(p []byte) (int, error) {
+    w.lock.Lock()
+    defer w.lock.Unlock()
+
+    w.buffer = append(w.buffer, p...)
+    for {
+        index := bytes.IndexByte(w.buffer, '\n')
+        if index < 0 {
+            break
+        }
+        w.writeLine(w.buffer[:index])
+        w.buffer = w.buffer[index+1:]
+    }
+    return len(p), nil
+}
+
+// Logs whatever is left in the buffer even if it is not a complete line
+func (w *PrefixWriter) Flush() {
+    w.lock.Lock()
+    defer w.lock.Unlock()
+
+    if len(w.buffer) > 0 {
+        w.writeLine(w.buffer)
+        w.buffer = nil
+    }
+}
+
+func (w *PrefixWriter) writeLine(line []byte) {
+    text := strings.TrimRight(string(line), "\r")
+    Writeln(w.level, "%s%s", w.color.Sprint(w.prefix), text)
+}
@@ -0,0 +1,40 @@
+package log
+
+import (
+    "bytes"
+    "testing"
+
+    "github.com/fatih/color"
+    "github.com/stretchr/testify/assert"
+)
+
+func TestPrefixWriter(t *testing.T) {
+    noColor := color.NoColor
+    color.NoColor = true
+    out := &bytes.Buffer{}
+    prevOut, prevErr := SetOutput(out, out)
+    defer func() {
+        SetOutput(prevOut, prevErr)
+        color.NoColor = noColor
+    }()
+
+    w := NewPrefixWriter("[blink] ", nil, INFO)
+    data := []byte("Scanning dependencies\r\n[ 50%] Build")
+    n, err := w.Write(data)
+    assert.Nil(t, err)
+    assert.Equal(t, len(data), n)
+    assert.Equal(t, "[blink] Scanning dependencies\n", out.String())
+
+    w.Write([]byte("ing main.cpp\n\n[100%] Linking"))
+    assert.Equal(t, "[blink] Scanning dependencies\n[blink] [ 50%] Building main.cpp\n[blink] \n", out.String())
+
+    w.Flush()
+    w.Flush()
+    assert.Equal(t, "[blink] Scanning dependencies\n[blink] [ 50%] Building main.cpp\n[blink] \n"+
+        "[blink] [100%] Linking\n", out.String())
+
+    out.Reset()
+    w = NewPrefixWriter("[tests] ", Red, ERR)
+    w.Write([]byte("error: 'x' was not declared\n"))
+    assert.Equal(t, "ERR [tests] error: 'x' was not declared\n", out.String())
+}
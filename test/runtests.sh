@@ -50,7 +50,12 @@ _test3() {
     wio clean --hard
     wio update
     wio build
+    wio build --profile release
     wio run
+    wio test
+    wio test --sanitize address,undefined
+    wio clean --profile release --hard
+    wio clean --sanitize address,undefined --hard
     wio clean
 }
 
@@ -70,7 +75,13 @@ _test5() {
     wio clean native-tests --verbose
     wio build native-tests --disable-warnings
     wio build avr-tests
+    wio size avr-tests --by-package --json size.json
+    wio upload avr-tests --programmer usbasp --dry-run
+    wio upload avr-tests --port /dev/ttyACM0,/dev/ttyACM1 --dry-run
+    wio upload avr-tests --port /dev/ttyUSB0 --upload-baud 57600 --reset none --dry-run
     wio run native-tests
+    wio test native-tests --junit report.xml
+    wio test native-tests --coverage
 }
 
 _test6() {
@@ -0,0 +1,22 @@
+if (BAREMETAL_IS_TOOLCHAIN_PROCESSED)
+    return()
+endif ()
+set(BAREMETAL_IS_TOOLCHAIN_PROCESSED True)
+set(CMAKE_SYSTEM_NAME Generic)
+
+# Prefer the avr-gcc shipped with the Arduino tools next to wio, then the one in the path
+set(AVR_TOOLS_PATH ${CMAKE_CURRENT_LIST_DIR}/../arduino/hardware/tools/avr/bin)
+find_program(AVR_CC avr-gcc HINTS ${AVR_TOOLS_PATH})
+find_program(AVR_CXX avr-g++ HINTS ${AVR_TOOLS_PATH})
+find_program(AVR_OBJCOPY avr-objcopy HINTS ${AVR_TOOLS_PATH})
+if (NOT AVR_CC OR NOT AVR_CXX OR NOT AVR_OBJCOPY)
+    message(FATAL_ERROR "Failed to find avr-gcc, avr-g++ and avr-objcopy!")
+endif ()
+
+# Set compilers
+set(CMAKE_C_COMPILER ${AVR_CC})
+set(CMAKE_ASM_COMPILER ${AVR_CC})
+set(CMAKE_CXX_COMPILER ${AVR_CXX})
+
+# the compilers cannot link a program without knowing the mcu
+set(CMAKE_TRY_COMPILE_TARGET_TYPE STATIC_LIBRARY)